@@ -34,6 +34,16 @@ type EventAttribute struct {
 	Key, Value string
 }
 
+// Attribute returns the value of the first attribute of e with the given key, or empty if there is none.
+func (e Event) Attribute(key string) string {
+	for _, attr := range e.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
 // TxFinder finds transactions given block at height.
 type TxFinder interface {
 	FindTxs(ctx context.Context, height int64) ([]Tx, error)
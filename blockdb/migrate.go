@@ -147,6 +147,24 @@ ON CONFLICT(git_sha) DO UPDATE SET git_sha=git_sha`, nowRFC3339(), gitSha)
 		return fmt.Errorf("create table tendermint_event: %w", err)
 	}
 
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS container_stats (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    container_name TEXT NOT NULL CHECK (length(container_name) > 0),
+    created_at TEXT NOT NULL CHECK (length(created_at) > 0),
+    cpu_percent REAL NOT NULL,
+    memory_usage INTEGER NOT NULL,
+    memory_limit INTEGER NOT NULL,
+    network_rx INTEGER NOT NULL,
+    network_tx INTEGER NOT NULL,
+    block_read INTEGER NOT NULL,
+    block_write INTEGER NOT NULL,
+    fk_test_id INTEGER,
+    FOREIGN KEY(fk_test_id) REFERENCES test_case(id) ON DELETE CASCADE
+)`)
+	if err != nil {
+		return fmt.Errorf("create table container_stats: %w", err)
+	}
+
 	// Creating views should be last migration step.
 	if err := upsertViews(tx); err != nil {
 		// Error already wrapped.
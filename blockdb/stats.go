@@ -0,0 +1,117 @@
+package blockdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ContainerStats is the resource usage of a container at a point in time,
+// so that the blockdb package does not depend directly on docker.
+type ContainerStats struct {
+	ContainerName string
+	When          time.Time
+
+	// CPUPercent is relative to a single CPU, e.g. 150 for one and a half CPUs.
+	CPUPercent float64
+
+	// Memory in bytes.
+	MemoryUsage, MemoryLimit uint64
+
+	// Total bytes received and sent over all networks.
+	NetworkRx, NetworkTx uint64
+
+	// Total bytes read from and written to block devices.
+	BlockRead, BlockWrite uint64
+}
+
+// StatsFinder samples the resource usage of the containers of a test.
+type StatsFinder interface {
+	FindContainerStats(ctx context.Context) ([]ContainerStats, error)
+}
+
+// StatsSaver saves samples of the resource usage of containers.
+type StatsSaver interface {
+	SaveContainerStats(ctx context.Context, stats []ContainerStats) error
+}
+
+// SaveContainerStats tracks samples of the resource usage of the containers of the test case.
+func (tc *TestCase) SaveContainerStats(ctx context.Context, stats []ContainerStats) error {
+	dbTx, err := tc.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dbTx.Rollback() }()
+
+	for _, s := range stats {
+		_, err := dbTx.ExecContext(ctx, `INSERT INTO container_stats(
+    container_name, created_at, cpu_percent, memory_usage, memory_limit, network_rx, network_tx, block_read, block_write, fk_test_id
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.ContainerName, s.When.UTC().Format(time.RFC3339), s.CPUPercent,
+			int64(s.MemoryUsage), int64(s.MemoryLimit), int64(s.NetworkRx), int64(s.NetworkTx), int64(s.BlockRead), int64(s.BlockWrite),
+			tc.id,
+		)
+		if err != nil {
+			return fmt.Errorf("insert into container_stats: %w", err)
+		}
+	}
+
+	return dbTx.Commit()
+}
+
+// StatsCollector saves container stats at regular intervals.
+type StatsCollector struct {
+	finder StatsFinder
+	log    *zap.Logger
+	rate   time.Duration
+	saver  StatsSaver
+	cancel context.CancelFunc
+}
+
+// NewStatsCollector creates a valid StatsCollector that samples every duration at rate.
+// Sampling takes about a second per container, so a rate of a few seconds is typical.
+func NewStatsCollector(log *zap.Logger, finder StatsFinder, saver StatsSaver, rate time.Duration) *StatsCollector {
+	return &StatsCollector{
+		finder: finder,
+		log:    log,
+		rate:   rate,
+		saver:  saver,
+	}
+}
+
+// Collect saves container stats until the context is done or Stop is called.
+// Failed samples are logged and skipped.
+func (p *StatsCollector) Collect(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	defer p.cancel()
+
+	tick := time.NewTicker(p.rate)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			stats, err := p.finder.FindContainerStats(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					p.log.Info("Failed to find container stats", zap.Error(err))
+				}
+				continue
+			}
+			if err := p.saver.SaveContainerStats(ctx, stats); err != nil {
+				p.log.Info("Failed to save container stats", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Stop terminates the Collect loop.
+// Stop is safe to be called concurrently and is safe to be called multiple times.
+//
+// If Collect has not been called, Stop panics.
+func (p *StatsCollector) Stop() {
+	p.cancel()
+}
@@ -9,8 +9,8 @@ import (
 	"time"
 
 	"github.com/decentrio/rollup-e2e-testing/blockdb"
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 	"github.com/decentrio/rollup-e2e-testing/ibc"
-	"github.com/docker/docker/client"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -25,10 +25,14 @@ type chainSet struct {
 
 	chains map[ibc.Chain]struct{}
 
+	// ensuredImages are the image references already built or pulled by the image preflight of the Setup.
+	ensuredImages []string
+
 	// The following fields are set during TrackBlocks, and used in Close.
-	trackerEg  *errgroup.Group
-	db         *sql.DB
-	collectors []*blockdb.Collector
+	trackerEg *errgroup.Group
+	// stopTracking stops the collectors started by TrackBlocks.
+	stopTracking context.CancelFunc
+	db           *sql.DB
 }
 
 func newChainSet(log *zap.Logger, chains []ibc.Chain) *chainSet {
@@ -48,12 +52,15 @@ func newChainSet(log *zap.Logger, chains []ibc.Chain) *chainSet {
 // Initialize concurrently calls Initialize against each chain in the set.
 // Each chain may run a docker pull command,
 // so with a cold image cache, running concurrently may save some time.
-func (cs *chainSet) Initialize(ctx context.Context, testName string, cli *client.Client, networkID string) error {
+func (cs *chainSet) Initialize(ctx context.Context, testName string, cli dockerutil.Runtime, networkID string) error {
 	var eg errgroup.Group
 
 	for c := range cs.chains {
 		c := c
 		eg.Go(func() error {
+			if ec, ok := c.(interface{ SetEnsuredImages(refs []string) }); ok {
+				ec.SetEnsuredImages(cs.ensuredImages)
+			}
 			if err := c.Initialize(ctx, testName, cli, networkID); err != nil {
 				return fmt.Errorf("failed to initialize chain %s: %w", c.Config().Name, err)
 			}
@@ -154,8 +161,10 @@ func (cs *chainSet) Start(ctx context.Context, testName string, additionalGenesi
 // This method is a nop if dbPath is blank.
 // The gitSha is used to pin a git commit to a test invocation. Thus, when a user is looking at historical
 // data they are able to determine which version of the code produced the results.
+// If statsInterval is non-zero, the resource usage of every container of the test is also sampled
+// at statsInterval and saved in the database.
 // Expected to be called after Start.
-func (cs chainSet) TrackBlocks(ctx context.Context, testName, dbPath, gitSha string) error {
+func (cs *chainSet) TrackBlocks(ctx context.Context, testName, dbPath, gitSha string, cli dockerutil.Runtime, statsInterval time.Duration) error {
 	if len(dbPath) == 0 {
 		// nop
 		return nil
@@ -181,9 +190,16 @@ func (cs chainSet) TrackBlocks(ctx context.Context, testName, dbPath, gitSha str
 		return fmt.Errorf("create test case in sqlite database: %w", err)
 	}
 
+	// The collectors run until ctx is done or Close is called.
+	ctx, cs.stopTracking = context.WithCancel(ctx)
 	cs.trackerEg = new(errgroup.Group)
-	cs.collectors = make([]*blockdb.Collector, len(cs.chains))
-	i := 0
+	if statsInterval > 0 {
+		statsCollector := blockdb.NewStatsCollector(cs.log, containerStatsFinder{cli: cli, testName: testName}, testCase, statsInterval)
+		cs.trackerEg.Go(func() error {
+			statsCollector.Collect(ctx)
+			return nil
+		})
+	}
 	for c := range cs.chains {
 		c := c
 		id := c.Config().ChainID
@@ -192,7 +208,6 @@ func (cs chainSet) TrackBlocks(ctx context.Context, testName, dbPath, gitSha str
 			fmt.Fprintf(os.Stderr, `Chain %s is not configured to save blocks; must implement "FindTxs(ctx context.Context, height int64) ([][]byte, error)"`+"\n", id)
 			return nil
 		}
-		j := i // Avoid closure on loop variable.
 		cs.trackerEg.Go(func() error {
 			chaindb, err := testCase.AddChain(ctx, id, c.Config().Type)
 			if err != nil {
@@ -201,11 +216,9 @@ func (cs chainSet) TrackBlocks(ctx context.Context, testName, dbPath, gitSha str
 			}
 			log := cs.log.With(zap.String("chain_id", id))
 			collector := blockdb.NewCollector(log, finder, chaindb, 100*time.Millisecond)
-			cs.collectors[j] = collector
 			collector.Collect(ctx)
 			return nil
 		})
-		i++
 	}
 
 	return nil
@@ -216,10 +229,8 @@ func (cs chainSet) TrackBlocks(ctx context.Context, testName, dbPath, gitSha str
 // Currently, it only frees resources from TrackBlocks.
 // Close is safe to call even if TrackBlocks was not called.
 func (cs *chainSet) Close() error {
-	for _, c := range cs.collectors {
-		if c != nil {
-			c.Stop()
-		}
+	if cs.stopTracking != nil {
+		cs.stopTracking()
 	}
 
 	var err error
@@ -231,3 +242,32 @@ func (cs *chainSet) Close() error {
 	}
 	return err
 }
+
+// containerStatsFinder samples the resource usage of the containers of a test for blockdb.
+type containerStatsFinder struct {
+	cli      dockerutil.Runtime
+	testName string
+}
+
+// FindContainerStats implements blockdb.StatsFinder.
+func (f containerStatsFinder) FindContainerStats(ctx context.Context) ([]blockdb.ContainerStats, error) {
+	samples, err := dockerutil.SampleContainerStats(ctx, f.cli, f.testName)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]blockdb.ContainerStats, len(samples))
+	for i, s := range samples {
+		stats[i] = blockdb.ContainerStats{
+			ContainerName: s.ContainerName,
+			When:          s.When,
+			CPUPercent:    s.CPUPercent,
+			MemoryUsage:   s.MemoryUsage,
+			MemoryLimit:   s.MemoryLimit,
+			NetworkRx:     s.NetworkRx,
+			NetworkTx:     s.NetworkTx,
+			BlockRead:     s.BlockRead,
+			BlockWrite:    s.BlockWrite,
+		}
+	}
+	return stats, nil
+}
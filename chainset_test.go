@@ -0,0 +1,50 @@
+package rollupe2etesting
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+)
+
+// listCountingRuntime counts the container listings, done once per container stats sample.
+type listCountingRuntime struct {
+	dockerutil.Runtime
+	lists atomic.Int64
+}
+
+func (r *listCountingRuntime) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	r.lists.Add(1)
+	return r.Runtime.ContainerList(ctx, options)
+}
+
+func TestChainSetCloseStopsStatsCollector(t *testing.T) {
+	t.Parallel()
+
+	rt := &listCountingRuntime{Runtime: dockerutil.NewFakeRuntime()}
+	cs := newChainSet(zaptest.NewLogger(t), nil)
+
+	dbPath := filepath.Join(t.TempDir(), "blocks.db")
+	require.NoError(t, cs.TrackBlocks(context.Background(), t.Name(), dbPath, "", rt, 10*time.Millisecond))
+	require.Eventually(t, func() bool { return rt.lists.Load() > 0 }, 10*time.Second, 10*time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- cs.Close() }()
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Close did not stop the stats collector")
+	}
+
+	sampled := rt.lists.Load()
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, sampled, rt.lists.Load())
+}
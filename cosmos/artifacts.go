@@ -0,0 +1,69 @@
+package cosmos
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+)
+
+var _ ibc.ArtifactCollector = (*CosmosChain)(nil)
+
+// CollectArtifacts implements ibc.ArtifactCollector. The files of a node are copied into a directory named after it.
+func (c *CosmosChain) CollectArtifacts(ctx context.Context, dir string) error {
+	var eg errgroup.Group
+	for _, n := range c.Nodes() {
+		n := n
+		eg.Go(func() error {
+			if err := n.collectArtifacts(ctx, filepath.Join(dir, n.Name())); err != nil {
+				return fmt.Errorf("failed to collect artifacts of node %s: %w", n.Name(), err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// collectArtifacts copies the config directory of the node into dir. The container may be stopped.
+func (node *Node) collectArtifacts(ctx context.Context, dir string) error {
+	if node.ContainerID() == "" {
+		return nil
+	}
+	rc, _, err := node.DockerClient.CopyFromContainer(ctx, node.ContainerID(), path.Join(node.snapshotHomeDir(), "config"))
+	if err != nil {
+		return fmt.Errorf("failed to copy config directory: %w", err)
+	}
+	defer rc.Close()
+	return dockerutil.UntarDir(dir, rc)
+}
+
+// ExportCommittedState implements ibc.ArtifactCollector. The full node is stopped for the export
+// and left stopped, as the state is exported once the test failed.
+func (c *CosmosChain) ExportCommittedState(ctx context.Context) (string, error) {
+	return c.getFullNode().exportCommittedState(ctx)
+}
+
+// exportCommittedState exports the state of the node at the last height committed to its data directory.
+// A running node is stopped first, since it keeps committing blocks while the state is read.
+// Only stdout is returned, so the logs of the export do not corrupt the exported JSON.
+func (node *Node) exportCommittedState(ctx context.Context) (string, error) {
+	if node.ContainerID() != "" && node.containerLifecycle.Running(ctx) == nil {
+		if err := node.containerLifecycle.StopContainer(ctx); err != nil {
+			return "", fmt.Errorf("failed to stop node %s: %w", node.Name(), err)
+		}
+	}
+
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	// Without --height, the state is exported at the last committed height.
+	stdout, _, err := node.ExecBin(ctx, "export")
+	if err != nil {
+		return "", err
+	}
+	return string(stdout), nil
+}
@@ -0,0 +1,82 @@
+package cosmos
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+)
+
+// stopRecordingRuntime records the stops of the container watched among the commands run.
+type stopRecordingRuntime struct {
+	*dockerutil.FakeRuntime
+
+	mu      sync.Mutex
+	watched string
+	events  []string
+}
+
+func (r *stopRecordingRuntime) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *stopRecordingRuntime) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	r.mu.Lock()
+	watched := containerID == r.watched
+	r.mu.Unlock()
+	if watched {
+		r.record("stop")
+	}
+	return r.FakeRuntime.ContainerStop(ctx, containerID, options)
+}
+
+func TestExportCommittedState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	srv := cometStatusServer(t)
+	_, srvPort, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	rt := &stopRecordingRuntime{FakeRuntime: dockerutil.NewFakeRuntime()}
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		rt.record(strings.Join(cmd[:2], " "))
+		if cmd[1] == "start" {
+			return dockerutil.FakeResult{Running: true}
+		}
+		return dockerutil.FakeResult{Stdout: []byte(`{"app_state":{}}`), Stderr: []byte("INF exporting state")}
+	}
+	rt.HostPortFunc = func(containerName string, port nat.Port) string {
+		if port == rpcPort {
+			return srvPort
+		}
+		return ""
+	}
+
+	chain := testChain(t)
+	node := NewNode(zaptest.NewLogger(t), true, chain, rt, "net", t.Name(), testImage, 0)
+	chain.Validators = Nodes{node}
+	require.NoError(t, node.CreateNodeContainer(ctx, nil))
+	require.NoError(t, node.StartContainer(ctx))
+	rt.mu.Lock()
+	rt.watched = node.ContainerID()
+	rt.mu.Unlock()
+
+	state, err := chain.ExportCommittedState(ctx)
+	require.NoError(t, err)
+	require.Equal(t, `{"app_state":{}}`, state)
+
+	// The node is stopped for the export, which reads the last committed height, and left stopped.
+	require.Equal(t, []string{"dymd start", "stop", "dymd export"}, rt.events)
+	require.Error(t, node.containerLifecycle.Running(ctx))
+}
@@ -0,0 +1,201 @@
+package cosmos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ChaosAction is a fault RunChaos applies to a node.
+type ChaosAction string
+
+const (
+	// ChaosPause freezes the node, then resumes it after the downtime.
+	ChaosPause ChaosAction = "pause"
+	// ChaosKill kills the node, then restarts it after the downtime.
+	ChaosKill ChaosAction = "kill"
+	// ChaosRestart gracefully restarts the node.
+	ChaosRestart ChaosAction = "restart"
+
+	chaosResume = "resume"
+
+	defaultChaosMinInterval = 10 * time.Second
+	defaultChaosMaxInterval = 30 * time.Second
+	defaultChaosDowntime    = 10 * time.Second
+
+	// chaosRecoveryTimeout bounds bringing back a paused or killed node once the context of RunChaos is done.
+	chaosRecoveryTimeout = 3 * time.Minute
+)
+
+// ChaosReporter is the interface RunChaos logs its actions to,
+// implemented by testreporter.ChaosReporter.
+type ChaosReporter interface {
+	TrackChaos(target, action string, when time.Time, err error)
+}
+
+// ChaosSchedule configures RunChaos.
+type ChaosSchedule struct {
+	// Seed of the schedule. The same seed and nodes produce the same sequence of actions,
+	// so a failing run can be replayed.
+	Seed int64
+
+	// Nodes the actions are applied to, e.g. chain.Validators or the rollapp sequencer node.
+	Nodes []*Node
+
+	// Actions to pick from. Defaults to all of them.
+	Actions []ChaosAction
+
+	// Duration of the test window. No action starts after it.
+	Duration time.Duration
+
+	// Bounds of the random wait between two actions. Default to 10s and 30s.
+	MinInterval, MaxInterval time.Duration
+
+	// Downtime is how long a node stays paused or killed. Defaults to 10s.
+	Downtime time.Duration
+
+	// Reporter optionally tracks every action.
+	Reporter ChaosReporter
+}
+
+// ChaosEvent is an action applied by RunChaos.
+type ChaosEvent struct {
+	When   time.Time
+	Node   string
+	Action string
+	Err    error
+}
+
+// RunChaos applies random actions of schedule to its nodes, one at a time, during the test window.
+// Every paused or killed node is brought back before the next action,
+// so all the nodes are running again when RunChaos returns.
+//
+// RunChaos blocks until the window ends and returns the applied actions,
+// and stops at the first action that fails or when ctx is done.
+// A node paused or killed when ctx is done is still resumed or restarted, with a fresh context, before RunChaos returns.
+func RunChaos(ctx context.Context, log *zap.Logger, schedule ChaosSchedule) ([]ChaosEvent, error) {
+	if len(schedule.Nodes) == 0 {
+		return nil, errors.New("chaos schedule has no nodes")
+	}
+	if schedule.Duration <= 0 {
+		return nil, errors.New("chaos schedule has no duration")
+	}
+
+	actions := schedule.Actions
+	if len(actions) == 0 {
+		actions = []ChaosAction{ChaosPause, ChaosKill, ChaosRestart}
+	}
+	minInterval, maxInterval := schedule.MinInterval, schedule.MaxInterval
+	if minInterval <= 0 {
+		minInterval = defaultChaosMinInterval
+	}
+	if maxInterval < minInterval {
+		maxInterval = max(minInterval, defaultChaosMaxInterval)
+	}
+	downtime := schedule.Downtime
+	if downtime <= 0 {
+		downtime = defaultChaosDowntime
+	}
+
+	log = log.With(zap.Int64("chaos_seed", schedule.Seed))
+	log.Info("Starting chaos schedule", zap.Duration("duration", schedule.Duration))
+
+	var (
+		rng    = rand.New(rand.NewSource(schedule.Seed))
+		end    = time.Now().Add(schedule.Duration)
+		events []ChaosEvent
+	)
+
+	track := func(node *Node, action string, err error) error {
+		e := ChaosEvent{When: time.Now(), Node: node.Name(), Action: action, Err: err}
+		events = append(events, e)
+
+		if err != nil {
+			log.Error("Chaos action failed", zap.String("node", e.Node), zap.String("action", action), zap.Error(err))
+		} else {
+			log.Info("Chaos action", zap.String("node", e.Node), zap.String("action", action))
+		}
+		if schedule.Reporter != nil {
+			schedule.Reporter.TrackChaos(e.Node, action, e.When, err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to %s node %s (chaos seed %d): %w", action, e.Node, schedule.Seed, err)
+		}
+		return nil
+	}
+
+	// bringBack resumes or restarts a node once ctx is done, so that no node is left down.
+	bringBack := func(node *Node, action string, undo func(context.Context) error) error {
+		ctx, cancel := context.WithTimeout(context.Background(), chaosRecoveryTimeout)
+		defer cancel()
+		return track(node, action, undo(ctx))
+	}
+
+	for {
+		wait := minInterval
+		if maxInterval > minInterval {
+			wait += time.Duration(rng.Int63n(int64(maxInterval - minInterval)))
+		}
+		node := schedule.Nodes[rng.Intn(len(schedule.Nodes))]
+		action := actions[rng.Intn(len(actions))]
+
+		if time.Now().Add(wait).After(end) {
+			break
+		}
+		if err := sleepCtx(ctx, wait); err != nil {
+			return events, err
+		}
+
+		switch action {
+		case ChaosPause:
+			if err := track(node, string(ChaosPause), node.Pause(ctx)); err != nil {
+				return events, err
+			}
+			if err := sleepCtx(ctx, downtime); err != nil {
+				return events, errors.Join(err, bringBack(node, chaosResume, node.Resume))
+			}
+			if err := track(node, chaosResume, node.Resume(ctx)); err != nil {
+				return events, err
+			}
+		case ChaosKill:
+			if err := track(node, string(ChaosKill), node.Kill(ctx)); err != nil {
+				return events, err
+			}
+			if err := sleepCtx(ctx, downtime); err != nil {
+				return events, errors.Join(err, bringBack(node, string(ChaosRestart), node.Restart))
+			}
+			if err := track(node, string(ChaosRestart), node.Restart(ctx)); err != nil {
+				return events, err
+			}
+		case ChaosRestart:
+			if err := track(node, string(ChaosRestart), node.Restart(ctx)); err != nil {
+				return events, err
+			}
+		default:
+			return events, fmt.Errorf("unknown chaos action %q", action)
+		}
+	}
+
+	log.Info("Finished chaos schedule", zap.Int("actions", len(events)))
+
+	return events, sleepCtx(ctx, time.Until(end))
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package cosmos
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+)
+
+// chaosReporterFunc adapts a function to ChaosReporter.
+type chaosReporterFunc func(target, action string, when time.Time, err error)
+
+func (f chaosReporterFunc) TrackChaos(target, action string, when time.Time, err error) {
+	f(target, action, when, err)
+}
+
+// chaosNodes returns n running validators of a chain, whose RPC is served by a started node.
+func chaosNodes(t *testing.T, n int) []*Node {
+	ctx := context.Background()
+	_, srvPort, err := net.SplitHostPort(cometStatusServer(t).Listener.Addr().String())
+	require.NoError(t, err)
+
+	rt := dockerutil.NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		return dockerutil.FakeResult{Running: strings.HasPrefix(strings.Join(cmd, " "), "dymd start")}
+	}
+	rt.HostPortFunc = func(containerName string, port nat.Port) string {
+		if port == rpcPort {
+			return srvPort
+		}
+		return ""
+	}
+
+	chain := testChain(t)
+	for i := 0; i < n; i++ {
+		node := NewNode(zaptest.NewLogger(t), true, chain, rt, "net", t.Name(), testImage, i)
+		require.NoError(t, node.CreateNodeContainer(ctx, nil))
+		require.NoError(t, node.containerLifecycle.StartContainer(ctx))
+		chain.Validators = append(chain.Validators, node)
+	}
+	return chain.Validators
+}
+
+// chaosSteps returns the node and action of every event.
+func chaosSteps(events []ChaosEvent) []string {
+	steps := make([]string, len(events))
+	for i, e := range events {
+		steps[i] = e.Node + " " + e.Action
+	}
+	return steps
+}
+
+func TestRunChaosSeed(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	nodes := chaosNodes(t, 3)
+	schedule := ChaosSchedule{
+		Seed:        42,
+		Nodes:       nodes,
+		Actions:     []ChaosAction{ChaosPause},
+		Duration:    200 * time.Millisecond,
+		MinInterval: time.Millisecond,
+		MaxInterval: 5 * time.Millisecond,
+		Downtime:    time.Millisecond,
+	}
+
+	first, err := RunChaos(ctx, zaptest.NewLogger(t), schedule)
+	require.NoError(t, err)
+	second, err := RunChaos(ctx, zaptest.NewLogger(t), schedule)
+	require.NoError(t, err)
+
+	// The number of actions fitting in the window depends on timing, not their sequence.
+	n := min(len(first), len(second))
+	require.GreaterOrEqual(t, n, 4)
+	require.Equal(t, chaosSteps(first[:n]), chaosSteps(second[:n]))
+
+	// Every pause is followed by the resume of the same node.
+	for _, events := range [][]ChaosEvent{first, second} {
+		require.Zero(t, len(events)%2)
+		for i := 0; i < len(events); i += 2 {
+			require.Equal(t, string(ChaosPause), events[i].Action)
+			require.Equal(t, chaosResume, events[i+1].Action)
+			require.Equal(t, events[i].Node, events[i+1].Node)
+		}
+	}
+	for _, node := range nodes {
+		require.NoError(t, node.containerLifecycle.Running(ctx))
+	}
+}
+
+func TestRunChaosCancelled(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		action ChaosAction
+		undo   string
+	}{
+		{ChaosPause, chaosResume},
+		{ChaosKill, string(ChaosRestart)},
+	} {
+		tc := tc
+		t.Run(string(tc.action), func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			nodes := chaosNodes(t, 1)
+
+			// The test is cancelled while the node is down.
+			events, err := RunChaos(ctx, zaptest.NewLogger(t), ChaosSchedule{
+				Seed:        1,
+				Nodes:       nodes,
+				Actions:     []ChaosAction{tc.action},
+				Duration:    time.Minute,
+				MinInterval: time.Millisecond,
+				MaxInterval: time.Millisecond,
+				Downtime:    time.Hour,
+				Reporter: chaosReporterFunc(func(target, action string, when time.Time, err error) {
+					if action == string(tc.action) {
+						cancel()
+					}
+				}),
+			})
+			require.ErrorIs(t, err, context.Canceled)
+			require.Equal(t, []string{
+				nodes[0].Name() + " " + string(tc.action),
+				nodes[0].Name() + " " + tc.undo,
+			}, chaosSteps(events))
+			require.NoError(t, events[1].Err)
+			require.NoError(t, nodes[0].containerLifecycle.Running(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package cosmos
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/testutil"
+)
+
+// Statuses of an IBC light client, as reported by QueryClientStatus.
+const (
+	ClientStatusActive  = "Active"
+	ClientStatusExpired = "Expired"
+	ClientStatusFrozen  = "Frozen"
+)
+
+// recoveryProposalBlocks is the number of blocks to wait for a client recovery proposal to pass.
+const recoveryProposalBlocks = 20
+
+// QueryClientStatus returns the status of the light client with clientID, e.g. ClientStatusExpired.
+func (c *CosmosChain) QueryClientStatus(ctx context.Context, clientID string) (string, error) {
+	res, err := c.getFullNode().QueryClientStatus(ctx, clientID)
+	if err != nil {
+		return "", fmt.Errorf("failed to query status of client %s: %w", clientID, err)
+	}
+	return res.Status, nil
+}
+
+// WaitForClientStatus polls the status of the light client with clientID until it is status or timeout elapses.
+func (c *CosmosChain) WaitForClientStatus(ctx context.Context, clientID, status string, timeout time.Duration) error {
+	var last string
+	err := testutil.WaitForCondition(timeout, 2*time.Second, func() (bool, error) {
+		s, err := c.QueryClientStatus(ctx, clientID)
+		if err != nil {
+			return false, err
+		}
+		last = s
+		return s == status, nil
+	})
+	if err != nil {
+		return fmt.Errorf("client %s on %s is %s, expected %s: %w", clientID, c.cfg.ChainID, last, status, err)
+	}
+	return nil
+}
+
+// ExpireClient stops r and waits until the light client with clientID on c expires,
+// trustingPeriod being the trusting period the client was created with.
+//
+// Tendermint clients take their trusting period from ibc.CreateClientOptions when the path is linked,
+// dymint clients from the trusting period the relayer chain configuration was added with.
+func ExpireClient(ctx context.Context, c *CosmosChain, r ibc.Relayer, rep ibc.RelayerExecReporter, clientID string, trustingPeriod time.Duration) error {
+	if err := r.StopRelayer(ctx, rep); err != nil {
+		return fmt.Errorf("failed to stop relayer: %w", err)
+	}
+
+	// The client expires once trustingPeriod elapsed since its last update, leave some room for block times.
+	return c.WaitForClientStatus(ctx, clientID, ClientStatusExpired, trustingPeriod+time.Minute)
+}
+
+// RecoverClient recovers the expired or frozen light client with clientID on c through governance,
+// for both 07-tendermint and 01-dymint clients.
+// It creates a substitute client on c tracking the same chain with r, on a path generated for it,
+// substitutes it for the subject client with an update client proposal voted by all validators,
+// and waits for the subject client to be active again. It returns the ID of the substitute client.
+//
+// The path pathName relaying over the subject client is left unchanged, so it relays again once recovered.
+func RecoverClient(
+	ctx context.Context,
+	c *CosmosChain,
+	r ibc.Relayer,
+	rep ibc.RelayerExecReporter,
+	pathName, clientID, keyName, deposit string,
+	opts ibc.CreateClientOptions,
+) (string, error) {
+	clients, err := r.GetClients(ctx, rep, c.cfg.ChainID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get clients: %w", err)
+	}
+	var counterparty string
+	for _, client := range clients {
+		if client.ClientID == clientID {
+			counterparty = client.ClientState.ChainID
+		}
+	}
+	if counterparty == "" {
+		return "", fmt.Errorf("client %s not found on %s", clientID, c.cfg.ChainID)
+	}
+
+	// Creating the substitute on pathName would point the path at a client without connection.
+	substitutePath := fmt.Sprintf("%s-substitute-%s", pathName, dockerutil.RandLowerCaseLetterString(6))
+	if err := r.GeneratePath(ctx, rep, c.cfg.ChainID, counterparty, substitutePath); err != nil {
+		return "", fmt.Errorf("failed to generate substitute path: %w", err)
+	}
+	if err := r.CreateClient(ctx, rep, c.cfg.ChainID, counterparty, substitutePath, opts); err != nil {
+		return "", fmt.Errorf("failed to create substitute client: %w", err)
+	}
+
+	substitute, err := newestClient(ctx, c, r, rep, clientID, counterparty)
+	if err != nil {
+		return "", err
+	}
+
+	height, err := c.Height(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get chain height: %w", err)
+	}
+
+	tx, err := c.SubmitUpdateClientProposal(ctx, keyName, clientID, substitute, deposit)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.VoteOnProposalAllValidators(ctx, tx.ProposalID, ProposalVoteYes); err != nil {
+		return "", fmt.Errorf("failed to vote on update client proposal: %w", err)
+	}
+
+	if _, err := PollForProposalStatus(ctx, c, height, height+recoveryProposalBlocks, tx.ProposalID, ProposalStatusPassed); err != nil {
+		return "", fmt.Errorf("update client proposal %s did not pass: %w", tx.ProposalID, err)
+	}
+
+	if err := c.WaitForClientStatus(ctx, clientID, ClientStatusActive, time.Minute); err != nil {
+		return "", err
+	}
+	return substitute, nil
+}
+
+// newestClient returns the client on c tracking counterparty with the highest sequence, other than clientID.
+func newestClient(ctx context.Context, c *CosmosChain, r ibc.Relayer, rep ibc.RelayerExecReporter, clientID, counterparty string) (string, error) {
+	clients, err := r.GetClients(ctx, rep, c.cfg.ChainID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get clients: %w", err)
+	}
+
+	var (
+		newest string
+		maxSeq = -1
+	)
+	for _, client := range clients {
+		if client.ClientID == clientID || client.ClientState.ChainID != counterparty {
+			continue
+		}
+		// Client IDs are <client type>-<sequence>, e.g. 07-tendermint-3.
+		seq, err := strconv.Atoi(client.ClientID[strings.LastIndex(client.ClientID, "-")+1:])
+		if err != nil {
+			continue
+		}
+		if seq > maxSeq {
+			newest, maxSeq = client.ClientID, seq
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("no substitute client found on %s tracking %s", c.cfg.ChainID, counterparty)
+	}
+	return newest, nil
+}
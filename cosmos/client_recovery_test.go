@@ -0,0 +1,180 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/relayer"
+	rly "github.com/decentrio/rollup-e2e-testing/relayer/rly"
+)
+
+// cometRPCServer serves the RPC of a node producing 5 blocks between status requests,
+// whose every transaction is tx, submitting the proposal 1.
+func cometRPCServer(t *testing.T, tx []byte) *httptest.Server {
+	var height atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var result string
+		switch req.Method {
+		case "status":
+			result = fmt.Sprintf(`{"node_info":{"protocol_version":{"p2p":"8","block":"11","app":"0"},"other":{}},`+
+				`"sync_info":{"latest_block_height":"%d","latest_block_time":"2024-01-01T00:00:00Z","catching_up":false},`+
+				`"validator_info":{"voting_power":"0"}}`, height.Add(5))
+		case "tx":
+			result = `{"hash":"0A0B","height":"10","index":0,"tx":"` + base64.StdEncoding.EncodeToString(tx) + `",` +
+				`"tx_result":{"code":0,"gas_wanted":"100","gas_used":"50","events":[` +
+				`{"type":"submit_proposal","attributes":[{"key":"proposal_id","value":"1","index":true}]}]}}`
+		case "block":
+			result = `{"block_id":{"hash":"","parts":{"total":0,"hash":""}},` +
+				`"block":{"header":{"height":"10","time":"2024-01-01T00:00:00Z"},"data":{"txs":[]},"evidence":{"evidence":[]}}}`
+		default:
+			http.Error(w, "unexpected method "+req.Method, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":` + result + `}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fakeRlyPaths models the paths and clients of an rly home, for the rly commands run by RecoverClient.
+type fakeRlyPaths struct {
+	mu      sync.Mutex
+	paths   map[string]ibc.PathIDs
+	clients map[string][]ibc.ClientOutput
+}
+
+func (f *fakeRlyPaths) run(cmd []string) dockerutil.FakeResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	args := strings.Join(cmd, " ")
+	switch {
+	case strings.HasPrefix(args, "rly paths new "):
+		f.paths[cmd[5]] = ibc.PathIDs{}
+	case strings.HasPrefix(args, "rly tx clients "):
+		return dockerutil.FakeResult{Stderr: []byte("tx clients must not run"), ExitCode: 1}
+	case strings.HasPrefix(args, "rly tx client "):
+		src, dst, path := cmd[3], cmd[4], cmd[5]
+		id := fmt.Sprintf("%s-%d", clientType(f.clients[src][0].ClientID), len(f.clients[src]))
+		f.clients[src] = append(f.clients[src], ibc.ClientOutput{ClientID: id, ClientState: ibc.ClientState{ChainID: dst}})
+		ids := f.paths[path]
+		ids.SrcClientID = id
+		f.paths[path] = ids
+	case strings.HasPrefix(args, "rly q clients "):
+		var out []byte
+		for _, c := range f.clients[cmd[3]] {
+			line, _ := json.Marshal(c)
+			out = append(append(out, line...), '\n')
+		}
+		return dockerutil.FakeResult{Stdout: out}
+	}
+	return dockerutil.FakeResult{}
+}
+
+// clientType returns the type of a client from its ID, e.g. 07-tendermint for 07-tendermint-0.
+func clientType(clientID string) string {
+	return clientID[:strings.LastIndex(clientID, "-")]
+}
+
+func TestRecoverClient(t *testing.T) {
+	t.Parallel()
+
+	for _, subject := range []string{"07-tendermint-0", "01-dymint-0"} {
+		subject := subject
+		t.Run(clientType(subject), func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			log := zaptest.NewLogger(t)
+			const counterparty = "rollappevm_1234-1"
+
+			chain := testChain(t)
+			enc := DefaultEncoding()
+			chain.cfg.EncodingConfig = &enc
+			tx, err := enc.TxConfig.TxEncoder()(enc.TxConfig.NewTxBuilder().GetTx())
+			require.NoError(t, err)
+			_, srvPort, err := net.SplitHostPort(cometRPCServer(t, tx).Listener.Addr().String())
+			require.NoError(t, err)
+
+			live := ibc.PathIDs{SrcClientID: subject, SrcConnectionID: "connection-0", DstClientID: "07-tendermint-0", DstConnectionID: "connection-0"}
+			paths := &fakeRlyPaths{
+				paths: map[string]ibc.PathIDs{"hub-ra": live},
+				clients: map[string][]ibc.ClientOutput{
+					chain.cfg.ChainID: {{ClientID: subject, ClientState: ibc.ClientState{ChainID: counterparty}}},
+					counterparty:      {{ClientID: "07-tendermint-0", ClientState: ibc.ClientState{ChainID: chain.cfg.ChainID}}},
+				},
+			}
+
+			var proposals []string
+			rt := dockerutil.NewFakeRuntime()
+			rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+				args := strings.Join(cmd, " ")
+				switch {
+				case cmd[0] == "rly":
+					return paths.run(cmd)
+				case cmd[0] == "sleep", strings.HasPrefix(args, "dymd start"):
+					return dockerutil.FakeResult{Running: true}
+				case strings.HasPrefix(args, "dymd tx gov submit-legacy-proposal update-client "):
+					proposals = append(proposals, strings.Join(cmd[5:7], " "))
+					return dockerutil.FakeResult{Stdout: []byte(`{"code":0,"txhash":"0A0B"}`)}
+				case strings.HasPrefix(args, "dymd tx gov vote 1 yes"):
+					return dockerutil.FakeResult{Stdout: []byte(`{"code":0,"txhash":"0A0B"}`)}
+				case strings.HasPrefix(args, "dymd query gov proposal 1"):
+					return dockerutil.FakeResult{Stdout: []byte(`{"proposal_id":"1","status":"` + ProposalStatusPassed + `"}`)}
+				case strings.HasPrefix(args, "dymd query ibc client status "+subject):
+					return dockerutil.FakeResult{Stdout: []byte(`{"status":"` + ClientStatusActive + `"}`)}
+				}
+				return dockerutil.FakeResult{}
+			}
+			rt.HostPortFunc = func(containerName string, port nat.Port) string {
+				if port == rpcPort {
+					return srvPort
+				}
+				return ""
+			}
+
+			node := NewNode(log, true, chain, rt, "net", t.Name(), testImage, 0)
+			chain.Validators = Nodes{node}
+			require.NoError(t, node.CreateNodeContainer(ctx, nil))
+			require.NoError(t, node.StartContainer(ctx))
+
+			r := rly.NewCosmosRelayer(log, t.Name(), rt, "rly", "net", relayer.ImagePull(false))
+			substitute, err := RecoverClient(ctx, chain, r, ibc.NopRelayerExecReporter{}, "hub-ra", subject, "validator", "10000000udym", ibc.DefaultClientOpts())
+			require.NoError(t, err)
+
+			// The substitute is a client of the same type, created on c only, on a path of its own.
+			require.Equal(t, clientType(subject)+"-1", substitute)
+			require.Equal(t, []string{subject + " " + substitute}, proposals)
+			require.Len(t, paths.clients[counterparty], 1)
+
+			// The live path keeps relaying over the recovered client.
+			require.Equal(t, live, paths.paths["hub-ra"])
+			require.Len(t, paths.paths, 2)
+		})
+	}
+}
@@ -5,12 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	sdkmath "cosmossdk.io/math"
 	"github.com/cosmos/cosmos-sdk/codec"
@@ -22,16 +22,14 @@ import (
 	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	govv1 "github.com/cosmos/cosmos-sdk/x/gov/types/v1"
 	paramsutils "github.com/cosmos/cosmos-sdk/x/params/client/utils"
-	transfertypes "github.com/cosmos/ibc-go/v7/modules/apps/transfer/types"
 	chanTypes "github.com/cosmos/ibc-go/v7/modules/core/04-channel/types"
 	"github.com/decentrio/rollup-e2e-testing/blockdb"
 	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/netchaos"
 	"github.com/decentrio/rollup-e2e-testing/testreporter"
 	"github.com/decentrio/rollup-e2e-testing/testutil"
-	dockertypes "github.com/docker/docker/api/types"
 	volumetypes "github.com/docker/docker/api/types/volume"
-	"github.com/docker/docker/client"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
@@ -40,6 +38,15 @@ import (
 
 var _ ibc.Chain = (*CosmosChain)(nil)
 
+const (
+	// haltHeightDelta is the number of blocks between submitting an upgrade proposal and the upgrade height,
+	// when the proposal does not specify one.
+	haltHeightDelta = 20
+
+	// upgradeTimeout bounds the time spent waiting for the halt height and for blocks after an upgrade.
+	upgradeTimeout = 3 * time.Minute
+)
+
 // CosmosChain is a local docker testnet for a Cosmos SDK chain.
 // Implements the ibc.Chain interface.
 type CosmosChain struct {
@@ -54,6 +61,16 @@ type CosmosChain struct {
 	log      *zap.Logger
 	keyring  keyring.Keyring
 	findTxMu sync.Mutex
+
+	chaosMu sync.Mutex
+	chaos   *netchaos.Network
+
+	// hostAddressHooks are called when a node starts on new host ports, see OnHostAddressesChange.
+	hostAddressMu    sync.Mutex
+	hostAddressHooks []func(ctx context.Context, old, new ibc.HostAddresses) error
+
+	// ensuredImages are the image references that are not built or pulled again, see SetEnsuredImages.
+	ensuredImages map[string]bool
 }
 
 func NewCosmosChain(testName string, chainConfig ibc.ChainConfig, numValidators int, numFullNodes int, log *zap.Logger) *CosmosChain {
@@ -150,7 +167,7 @@ func (c *CosmosChain) Config() ibc.ChainConfig {
 }
 
 // Implements Chain interface
-func (c *CosmosChain) Initialize(ctx context.Context, testName string, cli *client.Client, networkID string) error {
+func (c *CosmosChain) Initialize(ctx context.Context, testName string, cli dockerutil.Runtime, networkID string) error {
 	if err := c.initializeSidecars(ctx, testName, cli, networkID); err != nil {
 		return err
 	}
@@ -213,6 +230,27 @@ func (c *CosmosChain) GetHostGRPCAddress() string {
 	return c.getFullNode().hostGRPCPort
 }
 
+// OnHostAddressesChange implements ibc.HostAddressNotifier.
+func (c *CosmosChain) OnHostAddressesChange(fn func(ctx context.Context, old, new ibc.HostAddresses) error) {
+	c.hostAddressMu.Lock()
+	defer c.hostAddressMu.Unlock()
+	c.hostAddressHooks = append(c.hostAddressHooks, fn)
+}
+
+// notifyHostAddresses calls the hooks registered with OnHostAddressesChange.
+func (c *CosmosChain) notifyHostAddresses(ctx context.Context, old, new ibc.HostAddresses) error {
+	c.hostAddressMu.Lock()
+	hooks := append([]func(context.Context, ibc.HostAddresses, ibc.HostAddresses) error{}, c.hostAddressHooks...)
+	c.hostAddressMu.Unlock()
+
+	for _, fn := range hooks {
+		if err := fn(ctx, old, new); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // HomeDir implements ibc.Chain.
 func (c *CosmosChain) HomeDir() string {
 	return c.getFullNode().HomeDir()
@@ -319,6 +357,11 @@ func (c *CosmosChain) SendIBCTransfer(
 	if err != nil {
 		return tx, fmt.Errorf("send ibc transfer: %w", err)
 	}
+	return c.sentPacketTx(txHash)
+}
+
+// sentPacketTx returns the transaction with txHash, including the packet it sent.
+func (c *CosmosChain) sentPacketTx(txHash string) (tx ibc.Tx, _ error) {
 	txResp, err := c.GetTransaction(txHash)
 	if err != nil {
 		return tx, fmt.Errorf("failed to get transaction %s: %w", txHash, err)
@@ -397,11 +440,11 @@ func (c *CosmosChain) UpgradeLegacyProposal(ctx context.Context, keyName string,
 	return c.txProposal(txHash)
 }
 
-// UpgradeProposal submits a software-upgrade governance proposal to the chain.
+// RegisterIBCTokenDenomProposal submits a register-coin governance proposal to the chain.
 func (c *CosmosChain) RegisterIBCTokenDenomProposal(ctx context.Context, keyName, deposit, proposalPath string) error {
 	_, err := c.getFullNode().RegisterIBCTokenDenomProposal(ctx, keyName, deposit, proposalPath)
 	if err != nil {
-		return fmt.Errorf("failed to submit upgrade proposal: %w", err)
+		return fmt.Errorf("failed to submit register coin proposal: %w", err)
 	}
 	return nil
 }
@@ -445,6 +488,15 @@ func (c *CosmosChain) SubmitUpdateClientProposal(ctx context.Context, keyName, s
 	return c.txProposal(txHash)
 }
 
+// SubmitProposal submits a gov v1 proposal to the chain.
+func (c *CosmosChain) SubmitProposal(ctx context.Context, keyName string, prop TxProposalv1) (tx TxProposal, _ error) {
+	txHash, err := c.getFullNode().SubmitProposal(ctx, keyName, prop)
+	if err != nil {
+		return tx, fmt.Errorf("failed to submit gov v1 proposal: %w", err)
+	}
+	return c.txProposal(txHash)
+}
+
 // Build a gov v1 proposal type.
 func (c *CosmosChain) BuildProposal(messages []ProtoMessage, title, summary, metadata, depositStr, proposer string, expedited bool) (TxProposalv1, error) {
 	var propType TxProposalv1
@@ -552,7 +604,9 @@ func (c *CosmosChain) GetGasFeesInNativeDenom(gasPaid int64) int64 {
 	return int64(fees)
 }
 
-func (c *CosmosChain) UpgradeVersion(ctx context.Context, cli *client.Client, containerRepo, version string) {
+// UpgradeVersion sets the image of the chain and its nodes to containerRepo:version and ensures it is available.
+// The nodes run the new image once restarted.
+func (c *CosmosChain) UpgradeVersion(ctx context.Context, cli dockerutil.Runtime, containerRepo, version string) error {
 	c.cfg.Images[0].Version = version
 	c.cfg.Images[0].Repository = containerRepo
 	for _, n := range c.Validators {
@@ -563,34 +617,120 @@ func (c *CosmosChain) UpgradeVersion(ctx context.Context, cli *client.Client, co
 		n.Image.Version = version
 		n.Image.Repository = containerRepo
 	}
-	c.pullImages(ctx, cli)
+	return c.pullImages(ctx, cli)
 }
 
-func (c *CosmosChain) pullImages(ctx context.Context, cli *client.Client) {
-	for _, image := range c.Config().Images {
-		rc, err := cli.ImagePull(
-			ctx,
-			image.Repository+":"+image.Version,
-			dockertypes.ImagePullOptions{},
-		)
+// Upgrade performs an in-place software upgrade of the chain to containerRepo:version.
+// When prop is set, the upgrade proposal is submitted by keyName, voted on by all validators and
+// the nodes are swapped once the chain halts at the upgrade height. A zero prop.Height schedules
+// the upgrade haltHeightDelta blocks from now. When prop is nil, as for dymint based rollapps that
+// do not use x/upgrade, the nodes are swapped at the current height.
+// Upgrade returns once the upgraded chain produces blocks again.
+func (c *CosmosChain) Upgrade(ctx context.Context, cli dockerutil.Runtime, keyName string, prop *SoftwareUpgradeProposal, containerRepo, version string) error {
+	if prop != nil {
+		if err := c.upgradeProposal(ctx, keyName, prop); err != nil {
+			return err
+		}
+	}
+
+	if err := c.StopAllNodes(ctx); err != nil {
+		return fmt.Errorf("failed to stop nodes: %w", err)
+	}
+
+	if err := c.UpgradeVersion(ctx, cli, containerRepo, version); err != nil {
+		return fmt.Errorf("failed to upgrade chain %s to %s:%s: %w", c.cfg.ChainID, containerRepo, version, err)
+	}
+
+	if err := c.StartAllNodes(ctx); err != nil {
+		return fmt.Errorf("failed to start upgraded nodes: %w", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, upgradeTimeout)
+	defer cancel()
+	if err := testutil.WaitForBlocks(timeoutCtx, 5, c); err != nil {
+		return fmt.Errorf("chain %s did not produce blocks after upgrade: %w", c.cfg.ChainID, err)
+	}
+	return nil
+}
+
+// upgradeProposal submits and passes the upgrade proposal, then blocks until the chain halts at the upgrade height.
+func (c *CosmosChain) upgradeProposal(ctx context.Context, keyName string, prop *SoftwareUpgradeProposal) error {
+	height, err := c.Height(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain height: %w", err)
+	}
+	if prop.Height == 0 {
+		prop.Height = height + haltHeightDelta
+	}
+
+	tx, err := c.UpgradeLegacyProposal(ctx, keyName, *prop)
+	if err != nil {
+		return err
+	}
+
+	if err := c.VoteOnProposalAllValidators(ctx, tx.ProposalID, ProposalVoteYes); err != nil {
+		return fmt.Errorf("failed to vote on upgrade proposal: %w", err)
+	}
+
+	if _, err := PollForProposalStatus(ctx, c, height, prop.Height, tx.ProposalID, ProposalStatusPassed); err != nil {
+		return fmt.Errorf("upgrade proposal %s did not pass: %w", tx.ProposalID, err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, upgradeTimeout)
+	defer cancel()
+	for {
+		// The chain panics when it reaches the upgrade height, so the last committed block is the one before it.
+		height, err := c.Height(timeoutCtx)
 		if err != nil {
-			c.log.Error("Failed to pull image",
-				zap.Error(err),
-				zap.String("repository", image.Repository),
-				zap.String("tag", image.Version),
-			)
-		} else {
-			_, _ = io.Copy(io.Discard, rc)
-			_ = rc.Close()
+			return fmt.Errorf("failed to get chain height: %w", err)
+		}
+		if height >= prop.Height-1 {
+			return nil
+		}
+
+		select {
+		case <-timeoutCtx.Done():
+			return fmt.Errorf("chain %s did not reach upgrade height %d: %w", c.cfg.ChainID, prop.Height, timeoutCtx.Err())
+		case <-time.After(2 * time.Second):
 		}
 	}
 }
 
+func (c *CosmosChain) pullImages(ctx context.Context, cli dockerutil.Runtime) error {
+	for _, image := range c.Config().Images {
+		if err := c.pullImage(ctx, cli, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetEnsuredImages records the images already built or pulled, e.g. by the image preflight of the Setup,
+// so they are not built or pulled again. It must be called before Initialize.
+func (c *CosmosChain) SetEnsuredImages(refs []string) {
+	c.ensuredImages = make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		c.ensuredImages[ref] = true
+	}
+}
+
+// pullImage builds or pulls image according to its configuration, see ibc.DockerImage.Ensure,
+// unless it was already ensured.
+func (c *CosmosChain) pullImage(ctx context.Context, cli dockerutil.Runtime, image ibc.DockerImage) error {
+	if c.ensuredImages[image.Ref()] {
+		return nil
+	}
+	if err := image.Ensure(ctx, c.log, cli); err != nil {
+		return fmt.Errorf("failed to ensure image %s of chain %s: %w", image.Ref(), c.cfg.ChainID, err)
+	}
+	return nil
+}
+
 // NewNode constructs a new cosmos chain node with a docker volume.
 func (c *CosmosChain) NewNode(
 	ctx context.Context,
 	testName string,
-	cli *client.Client,
+	cli dockerutil.Runtime,
 	networkID string,
 	image ibc.DockerImage,
 	validator bool,
@@ -630,7 +770,7 @@ func (c *CosmosChain) NewNode(
 		if !cfg.ValidatorProcess {
 			continue
 		}
-		err = node.NewSidecarProcess(ctx, cfg.PreStart, cfg.ProcessName, cli, networkID, cfg.Image, cfg.HomeDir, cfg.Ports, cfg.StartCmd)
+		err = node.NewSidecarProcess(ctx, cfg.PreStart, cfg.ProcessName, cli, networkID, cfg.Image, cfg.HomeDir, cfg.Ports, cfg.StartCmd, cfg.Resources)
 		if err != nil {
 			return nil, err
 		}
@@ -642,11 +782,13 @@ func (c *CosmosChain) NewNode(
 func (c *CosmosChain) initializeNodes(
 	ctx context.Context,
 	testName string,
-	cli *client.Client,
+	cli dockerutil.Runtime,
 	networkID string,
 ) error {
 	chainCfg := c.Config()
-	c.pullImages(ctx, cli)
+	if err := c.pullImages(ctx, cli); err != nil {
+		return err
+	}
 	image := chainCfg.Images[0]
 
 	newVals := make(Nodes, c.numValidators)
@@ -1051,8 +1193,7 @@ func (c *CosmosChain) IBCTransfer(ctx context.Context, chainA, chainB ibc.Chain,
 	}
 
 	// Get the IBC denom
-	chainATokenDenom := transfertypes.GetPrefixedDenom(channel.Counterparty.PortID, channel.Counterparty.ChannelID, chainA.Config().Denom)
-	chainAIBCDenom := transfertypes.ParseDenomTrace(chainATokenDenom).IBCDenom()
+	chainAIBCDenom := IBCDenom(channel.Counterparty.PortID, channel.Counterparty.ChannelID, chainA.Config().Denom)
 
 	chainATX, err := chainA.SendIBCTransfer(ctx, channel.ChannelID, chainAUserAddr, transfer, options)
 	if err != nil {
@@ -1115,17 +1256,18 @@ func (c *CosmosChain) NewSidecarProcess(
 	preStart bool,
 	processName string,
 	testName string,
-	cli *client.Client,
+	cli dockerutil.Runtime,
 	networkID string,
 	image ibc.DockerImage,
 	homeDir string,
 	index int,
 	ports []string,
 	startCmd []string,
+	resources dockerutil.ContainerResources,
 ) error {
 	// Construct the SidecarProcess first so we can access its name.
 	// The SidecarProcess's VolumeName cannot be set until after we create the volume.
-	s := NewSidecar(c.log, false, preStart, c, cli, networkID, processName, testName, image, homeDir, index, ports, startCmd)
+	s := NewSidecar(c.log, false, preStart, c, cli, networkID, processName, testName, image, homeDir, index, ports, startCmd, resources)
 	v, err := cli.VolumeCreate(ctx, volumetypes.CreateOptions{
 		Labels: map[string]string{
 			dockerutil.CleanupLabel:   testName,
@@ -1154,33 +1296,21 @@ func (c *CosmosChain) NewSidecarProcess(
 func (c *CosmosChain) initializeSidecars(
 	ctx context.Context,
 	testName string,
-	cli *client.Client,
+	cli dockerutil.Runtime,
 	networkID string,
 ) error {
 	eg, egCtx := errgroup.WithContext(ctx)
 	for i, cfg := range c.cfg.SidecarConfigs {
 		i := i
 		cfg := cfg
-		rc, err := cli.ImagePull(
-			ctx,
-			cfg.Image.Repository+":"+cfg.Image.Version,
-			dockertypes.ImagePullOptions{},
-		)
-		if err != nil {
-			c.log.Error("Failed to pull image",
-				zap.Error(err),
-				zap.String("repository", cfg.Image.Repository),
-				zap.String("tag", cfg.Image.Version),
-			)
-		} else {
-			_, _ = io.Copy(io.Discard, rc)
-			_ = rc.Close()
+		if err := c.pullImage(ctx, cli, cfg.Image); err != nil {
+			return err
 		}
 		if cfg.ValidatorProcess {
 			continue
 		}
 		eg.Go(func() error {
-			err := c.NewSidecarProcess(egCtx, cfg.PreStart, cfg.ProcessName, testName, cli, networkID, cfg.Image, cfg.HomeDir, i, cfg.Ports, cfg.StartCmd)
+			err := c.NewSidecarProcess(egCtx, cfg.PreStart, cfg.ProcessName, testName, cli, networkID, cfg.Image, cfg.HomeDir, i, cfg.Ports, cfg.StartCmd, cfg.Resources)
 			if err != nil {
 				return err
 			}
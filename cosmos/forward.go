@@ -0,0 +1,184 @@
+package cosmos
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/decentrio/rollup-e2e-testing/blockdb"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/testutil"
+)
+
+// ForwardHop is an intermediate chain of a forwarded transfer, the channel the packet leaves it on
+// and the receiver on the chain at the other end of that channel.
+type ForwardHop struct {
+	Chain    *CosmosChain
+	Channel  *ibc.ChannelOutput
+	Receiver string
+}
+
+// HopResult is the outcome of a forwarded transfer on a single hop.
+// Exactly one of Ack and Timeout is set once the packet has been relayed.
+type HopResult struct {
+	// ChainID of the chain that sent the packet.
+	ChainID string
+	Height  int64
+	Packet  ibc.Packet
+	Ack     *ibc.PacketAcknowledgement
+	Timeout *ibc.PacketTimeout
+}
+
+// SendForwardedTransfer sends transfer from src over channel with a packet-forward memo routing it through hops,
+// e.g. rollapp -> hub -> rollapp, and follows the packet across each hop until it is acknowledged or times out.
+// transfer.Address is the receiver on the first intermediate chain; options.Memo is replaced by the forward memo.
+// The results are ordered from src to the last hop. If the packet is not forwarded by a hop, e.g. because it timed out,
+// the results up to that hop are returned with an error.
+func SendForwardedTransfer(
+	ctx context.Context,
+	src *CosmosChain,
+	channel *ibc.ChannelOutput,
+	sender string,
+	transfer ibc.WalletData,
+	hops []ForwardHop,
+	options ibc.TransferOptions,
+) ([]HopResult, error) {
+	forwards := make([]ibc.ForwardMetadata, len(hops))
+	startHeights := make([]int64, len(hops))
+	for i, hop := range hops {
+		forwards[i] = ibc.ForwardMetadata{
+			Receiver: hop.Receiver,
+			Port:     hop.Channel.PortID,
+			Channel:  hop.Channel.ChannelID,
+		}
+		h, err := hop.Chain.Height(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get height of %s: %w", hop.Chain.Config().ChainID, err)
+		}
+		startHeights[i] = h
+	}
+
+	memo, err := ibc.ForwardMemo(forwards...)
+	if err != nil {
+		return nil, err
+	}
+	options.Memo = memo
+
+	tx, err := src.SendIBCTransfer(ctx, channel.ChannelID, sender, transfer, options)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []HopResult{{ChainID: src.Config().ChainID, Height: tx.Height, Packet: tx.Packet}}
+	chains := []*CosmosChain{src}
+	var hopErr error
+	for i, hop := range hops {
+		prev := results[len(results)-1].Packet
+		packet, height, err := findForwardedPacket(ctx, hop.Chain, startHeights[i], prev, hop.Channel.ChannelID)
+		if err != nil {
+			hopErr = fmt.Errorf("packet was not forwarded by %s: %w", hop.Chain.Config().ChainID, err)
+			break
+		}
+		results = append(results, HopResult{ChainID: hop.Chain.Config().ChainID, Height: height, Packet: packet})
+		chains = append(chains, hop.Chain)
+	}
+
+	// Acknowledgements travel back from the last hop, so follow the packets in reverse order.
+	for i := len(results) - 1; i >= 0; i-- {
+		if err := followPacket(ctx, chains[i], &results[i]); err != nil {
+			return results, err
+		}
+	}
+	return results, hopErr
+}
+
+// followPacket polls chain until the packet in res is acknowledged or times out.
+func followPacket(ctx context.Context, chain *CosmosChain, res *HopResult) error {
+	ack, ackErr := testutil.PollForAck(ctx, chain, res.Height, res.Height+ackPollBlocks, res.Packet)
+	if ackErr == nil {
+		res.Ack = &ack
+		return nil
+	}
+
+	timeout, err := testutil.PollForTimeout(ctx, chain, res.Height, res.Height+ackPollBlocks, res.Packet)
+	if err != nil {
+		return fmt.Errorf("packet %d on %s/%s of %s was neither acknowledged nor timed out: %w",
+			res.Packet.Sequence, res.Packet.SourcePort, res.Packet.SourceChannel, res.ChainID, ackErr)
+	}
+	res.Timeout = &timeout
+	return nil
+}
+
+// findForwardedPacket finds the packet chain sent on channelID when it received prev.
+func findForwardedPacket(ctx context.Context, chain *CosmosChain, startHeight int64, prev ibc.Packet, channelID string) (ibc.Packet, int64, error) {
+	type found struct {
+		packet ibc.Packet
+		height int64
+	}
+	poll := func(ctx context.Context, height int64) (found, error) {
+		txs, err := chain.FindTxs(ctx, height)
+		if err != nil {
+			return found{}, err
+		}
+		for _, tx := range txs {
+			if !receivedPacket(tx.Events, prev) {
+				continue
+			}
+			for _, e := range tx.Events {
+				if e.Type != "send_packet" || e.Attribute("packet_src_channel") != channelID {
+					continue
+				}
+				packet, err := packetFromEvent(e)
+				if err != nil {
+					return found{}, err
+				}
+				return found{packet: packet, height: height}, nil
+			}
+		}
+		return found{}, testutil.ErrNotFound
+	}
+
+	bp := testutil.BlockPoller[found]{CurrentHeight: chain.Height, PollFunc: poll}
+	res, err := bp.DoPoll(ctx, startHeight, startHeight+ackPollBlocks)
+	if err != nil {
+		return ibc.Packet{}, 0, fmt.Errorf("forwarded packet not found on %s: %w", chain.Config().ChainID, err)
+	}
+	return res.packet, res.height, nil
+}
+
+// receivedPacket reports whether events contain the receipt of packet.
+func receivedPacket(events []blockdb.Event, packet ibc.Packet) bool {
+	for _, e := range events {
+		if e.Type == "recv_packet" &&
+			e.Attribute("packet_sequence") == strconv.FormatUint(packet.Sequence, 10) &&
+			e.Attribute("packet_dst_port") == packet.DestPort &&
+			e.Attribute("packet_dst_channel") == packet.DestChannel {
+			return true
+		}
+	}
+	return false
+}
+
+// packetFromEvent builds the packet described by a send_packet event.
+func packetFromEvent(e blockdb.Event) (ibc.Packet, error) {
+	seq := e.Attribute("packet_sequence")
+	seqNum, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return ibc.Packet{}, fmt.Errorf("invalid packet sequence from events %s: %w", seq, err)
+	}
+	timeoutTs := e.Attribute("packet_timeout_timestamp")
+	timeoutNano, err := strconv.ParseUint(timeoutTs, 10, 64)
+	if err != nil {
+		return ibc.Packet{}, fmt.Errorf("invalid packet timestamp timeout %s: %w", timeoutTs, err)
+	}
+	return ibc.Packet{
+		Sequence:         seqNum,
+		SourcePort:       e.Attribute("packet_src_port"),
+		SourceChannel:    e.Attribute("packet_src_channel"),
+		DestPort:         e.Attribute("packet_dst_port"),
+		DestChannel:      e.Attribute("packet_dst_channel"),
+		Data:             []byte(e.Attribute("packet_data")),
+		TimeoutHeight:    e.Attribute("packet_timeout_height"),
+		TimeoutTimestamp: ibc.Nanoseconds(timeoutNano),
+	}, nil
+}
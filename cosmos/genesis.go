@@ -1,10 +1,12 @@
 package cosmos
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/decentrio/rollup-e2e-testing/ibc"
 	"github.com/icza/dyno"
@@ -53,3 +55,37 @@ func ModifyGenesis(genesisKV []GenesisKV) func(ibc.ChainConfig, []byte) ([]byte,
 		return out, nil
 	}
 }
+
+// ForkGenesis turns the output of an `export` command into a genesis file for a new chain.
+// The chain ID is replaced with chainID, the height and genesis time are reset,
+// and the exported app state (including bank balances) is kept as is.
+func ForkGenesis(exported []byte, chainID string) ([]byte, error) {
+	// Older versions print the exported state to stderr alongside log lines,
+	// so skip anything before the opening brace of the JSON document, and decode only that document
+	// since log lines may follow it.
+	start := bytes.IndexByte(exported, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("exported state does not contain a json document")
+	}
+
+	g := make(map[string]interface{})
+	if err := json.NewDecoder(bytes.NewReader(exported[start:])).Decode(&g); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal exported state: %w", err)
+	}
+
+	if err := dyno.Set(g, chainID, "chain_id"); err != nil {
+		return nil, fmt.Errorf("failed to set chain id in genesis json: %w", err)
+	}
+	if err := dyno.Set(g, "1", "initial_height"); err != nil {
+		return nil, fmt.Errorf("failed to set initial height in genesis json: %w", err)
+	}
+	if err := dyno.Set(g, time.Now().UTC().Format(time.RFC3339Nano), "genesis_time"); err != nil {
+		return nil, fmt.Errorf("failed to set genesis time in genesis json: %w", err)
+	}
+
+	out, err := json.Marshal(g)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal genesis bytes to json: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package cosmos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkGenesis(t *testing.T) {
+	t.Parallel()
+
+	const state = `{"chain_id":"rollappevm_1234-1","initial_height":"42","genesis_time":"2024-01-01T00:00:00Z",` +
+		`"app_state":{"bank":{"balances":[{"address":"ethm1","coins":[{"denom":"urax","amount":"10"}]}]}}}`
+
+	for name, exported := range map[string]string{
+		"stdout":        state + "\n",
+		"leading logs":  "I[2024-01-01|00:00:00.000] exporting state module=server\n" + state,
+		"trailing logs": state + "\n" + `{"level":"info","module":"server","msg":"exported state"}` + "\nI[2024-01-01|00:00:01.000] done\n",
+	} {
+		exported := exported
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			before := time.Now().UTC()
+			genesis, err := ForkGenesis([]byte(exported), "rollappevm_5678-1")
+			require.NoError(t, err)
+
+			var g struct {
+				ChainID       string          `json:"chain_id"`
+				InitialHeight string          `json:"initial_height"`
+				GenesisTime   time.Time       `json:"genesis_time"`
+				AppState      json.RawMessage `json:"app_state"`
+			}
+			require.NoError(t, json.Unmarshal(genesis, &g))
+			require.Equal(t, "rollappevm_5678-1", g.ChainID)
+			require.Equal(t, "1", g.InitialHeight)
+			require.False(t, g.GenesisTime.Before(before))
+			require.JSONEq(t, `{"bank":{"balances":[{"address":"ethm1","coins":[{"denom":"urax","amount":"10"}]}]}}`, string(g.AppState))
+		})
+	}
+
+	_, err := ForkGenesis([]byte("Error: no state\n"), "rollappevm_5678-1")
+	require.Error(t, err)
+}
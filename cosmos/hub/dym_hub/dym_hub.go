@@ -8,6 +8,7 @@ import (
 	"math"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +17,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/decentrio/rollup-e2e-testing/cosmos"
+	"github.com/decentrio/rollup-e2e-testing/cosmos/rollapp/dym_rollapp"
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 	"github.com/decentrio/rollup-e2e-testing/dymension"
 
 	"github.com/decentrio/rollup-e2e-testing/ibc"
@@ -42,6 +45,11 @@ const (
 	sequencerName = "sequencer"
 	maxSequencers = "5"
 	valKey        = "validator"
+
+	// proposalBlocks is the number of hub blocks a governance proposal has to pass in.
+	proposalBlocks = 20
+	// stateUpdateTimeout bounds the time waiting for the hub to accept a new rollapp state update.
+	stateUpdateTimeout = 5 * time.Minute
 )
 
 func NewDymHub(testName string, chainConfig ibc.ChainConfig, numValidators int, numFullNodes int, log *zap.Logger, extraFlags map[string]interface{}) *DymHub {
@@ -275,76 +283,46 @@ func (c *DymHub) Start(testName string, ctx context.Context, additionalGenesisWa
 	if len(c.rollApps) == 0 {
 		return nil
 	}
-	rollApps := c.rollApps
-	for _, r := range rollApps {
-		r := r
-		rollAppChainID := r.(ibc.Chain).GetChainID()
-		keyDir := r.GetSequencerKeyDir()
-		seq := r.GetSequencer()
-
-		if err := c.GetNode().CreateKeyWithKeyDir(ctx, sequencerName, keyDir); err != nil {
-			return err
-		}
-		sequencer, err := c.AccountKeyBech32WithKeyDir(ctx, sequencerName, keyDir)
-		if err != nil {
-			return err
-		}
-		amount := sdkmath.NewInt(10_000_000_000_000).MulRaw(100_000_000)
-		fund := ibc.WalletData{
-			Address: sequencer,
-			Denom:   c.Config().Denom,
-			Amount:  amount,
-		}
-		if err := c.SendFunds(ctx, "faucet", fund); err != nil {
+	for _, r := range c.rollApps {
+		if err := c.registerRollApp(ctx, r, bech32); err != nil {
 			return err
 		}
+	}
 
-		// hasFlagGenesisPath, ok := c.extraFlags["genesis-accounts-path"].(bool)
-		flags := map[string]string{}
-		// flags["transfers-enabled"] = "true"
-		// if hasFlagGenesisPath && ok {
-		// 	flags["genesis-accounts-path"] = validator0.HomeDir() + "/" + rollAppChainID + "_genesis_accounts.json"
-		// }
-
-		// Write denommetadata file
-		// denommetadata := []banktypes.Metadata{
-		// 	{
-		// 		Description: fmt.Sprintf("rollapp %s native token", rollAppChainID),
-		// 		Base:        r.(ibc.Chain).Config().Denom,
-		// 		DenomUnits: []*banktypes.DenomUnit{
-		// 			{
-		// 				Denom:    r.(ibc.Chain).Config().Denom,
-		// 				Exponent: 0,
-		// 			},
-		// 			{
-		// 				Denom:    "rax",
-		// 				Exponent: 6,
-		// 			},
-		// 		},
-		// 		Name:    fmt.Sprintf("%s %s", rollAppChainID, r.(ibc.Chain).Config().Denom),
-		// 		Symbol:  "URAX",
-		// 		Display: "rax",
-		// 	},
-		// }
-
-		// fileBz, err := json.MarshalIndent(denommetadata, "", "    ")
-		// if err != nil {
-		// 	return err
-		// }
-
-		// err = validator0.WriteFile(ctx, fileBz, "denommetadata.json")
-		// if err != nil {
-		// 	return err
-		// }
-		// metadataFileDir := validator0.HomeDir() + "/denommetadata.json"
-
-		if err := c.RegisterRollAppToHub(ctx, sequencerName, bech32, rollAppChainID, sequencer, r.(ibc.Chain).Config().Bech32Prefix, keyDir, flags); err != nil {
-			return fmt.Errorf("failed to start chain %s: %w", c.Config().Name, err)
-		}
+	return nil
+}
 
-		if err := c.RegisterSequencerToHub(ctx, sequencerName, rollAppChainID, seq, keyDir); err != nil {
-			return fmt.Errorf("failed to start chain %s: %w", c.Config().Name, err)
-		}
+// registerRollApp funds the sequencer of the rollapp and registers the rollapp and its sequencer on the hub.
+func (c *DymHub) registerRollApp(ctx context.Context, r ibc.RollApp, bech32 string) error {
+	rollAppChainID := r.(ibc.Chain).GetChainID()
+	keyDir := r.GetSequencerKeyDir()
+	seq := r.GetSequencer()
+
+	if err := c.GetNode().CreateKeyWithKeyDir(ctx, sequencerName, keyDir); err != nil {
+		return err
+	}
+	sequencer, err := c.AccountKeyBech32WithKeyDir(ctx, sequencerName, keyDir)
+	if err != nil {
+		return err
+	}
+	amount := sdkmath.NewInt(10_000_000_000_000).MulRaw(100_000_000)
+	fund := ibc.WalletData{
+		Address: sequencer,
+		Denom:   c.Config().Denom,
+		Amount:  amount,
+	}
+	if err := c.SendFunds(ctx, "faucet", fund); err != nil {
+		return err
+	}
+
+	flags := map[string]string{}
+
+	if err := c.RegisterRollAppToHub(ctx, sequencerName, bech32, rollAppChainID, sequencer, r.(ibc.Chain).Config().Bech32Prefix, keyDir, flags); err != nil {
+		return fmt.Errorf("failed to start chain %s: %w", c.Config().Name, err)
+	}
+
+	if err := c.RegisterSequencerToHub(ctx, sequencerName, rollAppChainID, seq, keyDir); err != nil {
+		return fmt.Errorf("failed to start chain %s: %w", c.Config().Name, err)
 	}
 
 	return nil
@@ -365,79 +343,123 @@ func (c *DymHub) SetupRollAppWithExistHub(ctx context.Context) error {
 	if len(c.rollApps) == 0 {
 		return nil
 	}
-	rollApps := c.rollApps
-	for _, r := range rollApps {
-		r := r
-		rollAppChainID := r.(ibc.Chain).GetChainID()
-		keyDir := r.GetSequencerKeyDir()
-		seq := r.GetSequencer()
-
-		if err := c.GetNode().CreateKeyWithKeyDir(ctx, sequencerName, keyDir); err != nil {
+	for _, r := range c.rollApps {
+		if err := c.registerRollApp(ctx, r, bech32); err != nil {
 			return err
 		}
+	}
 
-		sequencer, err := c.AccountKeyBech32WithKeyDir(ctx, sequencerName, keyDir)
+	return nil
+}
+
+// ForkRollApp halts source, exports its state at height and starts fork from that state under
+// the fork's chain ID. The fork must not have been initialized yet; it is registered on the hub
+// together with its sequencer before it starts producing blocks.
+//
+// The exported state is not reconciled with the sequencer of the fork: it keeps the validator set of source,
+// while the fork's sequencer signs with the new key created when the fork is initialized, which is the key
+// registered on the hub. Only rollapps whose app state does not pin the sequencer key, e.g. as the consensus key
+// of a genesis validator, can be forked this way.
+func (c *DymHub) ForkRollApp(ctx context.Context, testName string, cli dockerutil.Runtime, networkID string, source, fork *dym_rollapp.DymRollApp, height int64) error {
+	forkChainID := fork.Config().ChainID
+
+	genesis, err := source.ExportForkGenesis(ctx, height, forkChainID)
+	if err != nil {
+		return err
+	}
+
+	if err := fork.Initialize(ctx, testName, cli, networkID); err != nil {
+		return fmt.Errorf("failed to initialize chain %s: %w", fork.Config().Name, err)
+	}
+
+	if err := fork.Configuration(testName, ctx, forkChainID, genesis); err != nil {
+		return fmt.Errorf("failed to configuration chain %s: %w", fork.Config().Name, err)
+	}
+
+	bech32, err := c.Validators[0].AccountKeyBech32(ctx, valKey)
+	if err != nil {
+		return err
+	}
+	if err := c.registerRollApp(ctx, fork, bech32); err != nil {
+		return err
+	}
+	c.SetRollApp(fork)
+
+	if err := fork.Start(testName, ctx); err != nil {
+		return fmt.Errorf("failed to start chain %s: %w", fork.Config().Name, err)
+	}
+	return nil
+}
+
+// UpgradeRollApp drives a rollapp software upgrade coordinated through the hub.
+// The rollapp module of the hub this package targets (dymension v3) has no message setting the version of a rollapp:
+// the version is carried by every state update, see the version field of MsgUpdateState in
+// proto/dymensionxyz/dymension/rollapp/tx.proto, and recorded in the StateInfo of the update.
+// If prop has messages, e.g. parameter changes the new software relies on, it is submitted by keyName
+// and passed by all hub validators first. Then the sequencer and full nodes of the rollapp are swapped to
+// containerRepo:version. UpgradeRollApp returns once the hub accepts a new state update of the rollapp,
+// carrying stateVersion unless it is empty.
+func (c *DymHub) UpgradeRollApp(ctx context.Context, cli dockerutil.Runtime, keyName string, rollApp *dym_rollapp.DymRollApp, prop cosmos.TxProposalv1, containerRepo, version, stateVersion string) error {
+	rollAppChainID := rollApp.Config().ChainID
+
+	stateIndex, err := c.latestStateIndex(ctx, rollAppChainID)
+	if err != nil {
+		return err
+	}
+
+	if len(prop.Messages) > 0 {
+		height, err := c.Height(ctx)
 		if err != nil {
-			return err
-		}
-		amount := sdkmath.NewInt(10_000_000_000_000).MulRaw(100_000_000)
-		fund := ibc.WalletData{
-			Address: sequencer,
-			Denom:   c.Config().Denom,
-			Amount:  amount,
+			return fmt.Errorf("failed to get hub height: %w", err)
 		}
-		if err := c.SendFunds(ctx, "faucet", fund); err != nil {
+
+		tx, err := c.SubmitProposal(ctx, keyName, prop)
+		if err != nil {
 			return err
 		}
 
-		// hasFlagGenesisPath, ok := c.extraFlags["genesis-accounts-path"].(bool)
-		flags := map[string]string{}
-		// if hasFlagGenesisPath && ok {
-		// 	flags["genesis-accounts-path"] = validator0.HomeDir() + "/" + rollAppChainID + "_genesis_accounts.json"
-		// }
-
-		// Write denommetadata file
-		// denommetadata := []banktypes.Metadata{
-		// 	{
-		// 		Description: fmt.Sprintf("rollapp %s native token", rollAppChainID),
-		// 		Base:        r.(ibc.Chain).Config().Denom,
-		// 		DenomUnits: []*banktypes.DenomUnit{
-		// 			{
-		// 				Denom:    r.(ibc.Chain).Config().Denom,
-		// 				Exponent: 0,
-		// 			},
-		// 			{
-		// 				Denom:    "rax",
-		// 				Exponent: 6,
-		// 			},
-		// 		},
-		// 		Name:    fmt.Sprintf("%s %s", rollAppChainID, r.(ibc.Chain).Config().Denom),
-		// 		Symbol:  "URAX",
-		// 		Display: "rax",
-		// 	},
-		// }
-
-		// fileBz, err := json.MarshalIndent(denommetadata, "", "    ")
-		// if err != nil {
-		// 	return err
-		// }
-
-		// err = validator0.WriteFile(ctx, fileBz, "denommetadata.json")
-		// if err != nil {
-		// 	return err
-		// }
-		// metadataFileDir := validator0.HomeDir() + "/denommetadata.json"
-
-		if err := c.RegisterRollAppToHub(ctx, sequencerName, bech32, rollAppChainID, sequencer, r.(ibc.Chain).Config().Bech32Prefix, keyDir, flags); err != nil {
-			return fmt.Errorf("failed to start chain %s: %w", c.Config().Name, err)
+		if err := c.VoteOnProposalAllValidators(ctx, tx.ProposalID, cosmos.ProposalVoteYes); err != nil {
+			return fmt.Errorf("failed to vote on proposal %s: %w", tx.ProposalID, err)
 		}
 
-		if err := c.RegisterSequencerToHub(ctx, sequencerName, rollAppChainID, seq, keyDir); err != nil {
-			return fmt.Errorf("failed to start chain %s: %w", c.Config().Name, err)
+		if _, err := cosmos.PollForProposalStatus(ctx, c.CosmosChain, height, height+proposalBlocks, tx.ProposalID, cosmos.ProposalStatusPassed); err != nil {
+			return fmt.Errorf("proposal %s did not pass: %w", tx.ProposalID, err)
 		}
 	}
 
-	return nil
+	// Dymint based rollapps do not use x/upgrade, so the nodes are swapped at the current height.
+	if err := rollApp.Upgrade(ctx, cli, "", nil, containerRepo, version); err != nil {
+		return fmt.Errorf("failed to upgrade rollapp %s: %w", rollAppChainID, err)
+	}
+
+	return testutil.WaitForCondition(stateUpdateTimeout, 2*time.Second, func() (bool, error) {
+		state, err := c.QueryRollappState(ctx, rollAppChainID, false)
+		if err != nil {
+			return false, nil
+		}
+		index, err := strconv.ParseInt(state.StateInfo.StateInfoIndex.Index, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse state index of rollapp %s: %w", rollAppChainID, err)
+		}
+		return index > stateIndex && (stateVersion == "" || state.StateInfo.Version == stateVersion), nil
+	})
+}
+
+// latestStateIndex returns the index of the latest state update of a rollapp on the hub.
+// A rollapp without any state update yet has no latest index, which is reported as zero.
+func (c *DymHub) latestStateIndex(ctx context.Context, rollAppChainID string) (int64, error) {
+	latest, err := c.QueryLatestStateIndex(ctx, rollAppChainID, false)
+	if err != nil {
+		if strings.Contains(err.Error(), "code = NotFound") {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to query latest state index of rollapp %s: %w", rollAppChainID, err)
+	}
+	index, err := strconv.ParseInt(latest.StateIndex.Index, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse latest state index of rollapp %s: %w", rollAppChainID, err)
+	}
+	return index, nil
 }
 
 // RegisterSequencerToHub register sequencer for rollapp on settlement.
@@ -0,0 +1,76 @@
+package dym_hub
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+)
+
+func TestLatestStateIndex(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		result  dockerutil.FakeResult
+		want    int64
+		wantErr string
+	}{
+		{
+			name:   "latest index",
+			result: dockerutil.FakeResult{Stdout: []byte(`{"stateIndex":{"rollappId":"rollappevm_1234-1","index":"7"}}`)},
+			want:   7,
+		},
+		{
+			name:   "no state update yet",
+			result: dockerutil.FakeResult{Stderr: []byte("Error: rpc error: code = NotFound desc = not found"), ExitCode: 1},
+			want:   0,
+		},
+		{
+			name:    "query failure",
+			result:  dockerutil.FakeResult{Stderr: []byte("Error: post failed: connection refused"), ExitCode: 1},
+			wantErr: "failed to query latest state index of rollapp rollappevm_1234-1",
+		},
+		{
+			name:    "malformed index",
+			result:  dockerutil.FakeResult{Stdout: []byte(`{"stateIndex":{"rollappId":"rollappevm_1234-1","index":"seven"}}`)},
+			wantErr: "failed to parse latest state index of rollapp rollappevm_1234-1",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			rt := dockerutil.NewFakeRuntime()
+			rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+				if strings.Contains(strings.Join(cmd, " "), "rollapp latest-state-index rollappevm_1234-1") {
+					return tc.result
+				}
+				return dockerutil.FakeResult{}
+			}
+
+			hub := NewDymHub(t.Name(), ibc.ChainConfig{
+				Type:    "hub-dym",
+				Name:    "dymension",
+				ChainID: "dymension_100-1",
+				Bin:     "dymd",
+				Images:  []ibc.DockerImage{{Repository: "dymd", Version: "v1", UidGid: "1025:1025"}},
+			}, 1, 1, zaptest.NewLogger(t), nil)
+			require.NoError(t, hub.Initialize(ctx, t.Name(), rt, "net"))
+
+			index, err := hub.latestStateIndex(ctx, "rollappevm_1234-1")
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, index)
+		})
+	}
+}
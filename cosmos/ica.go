@@ -0,0 +1,126 @@
+package cosmos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/gogoproto/proto"
+	icatypes "github.com/cosmos/ibc-go/v7/modules/apps/27-interchain-accounts/types"
+	chanTypes "github.com/cosmos/ibc-go/v7/modules/core/04-channel/types"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/testutil"
+)
+
+// RegisterInterchainAccount registers an interchain account owned by keyName on the host chain at the other end of connectionID.
+// The account exists once a relayer completed the ICA channel handshake, see WaitForInterchainAccount.
+// If version is empty, the default ICS-27 metadata of the connection is used.
+func (c *CosmosChain) RegisterInterchainAccount(ctx context.Context, keyName, connectionID, version string) error {
+	txHash, err := c.getFullNode().RegisterInterchainAccount(ctx, keyName, connectionID, version)
+	if err != nil {
+		return fmt.Errorf("failed to register interchain account: %w", err)
+	}
+
+	txResp, err := c.GetTransaction(txHash)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction %s: %w", txHash, err)
+	}
+	if txResp.Code != 0 {
+		return fmt.Errorf("error in transaction (code: %d): %s", txResp.Code, txResp.RawLog)
+	}
+	return nil
+}
+
+// QueryInterchainAccount returns the host chain address of the interchain account owned by owner over connectionID.
+func (c *CosmosChain) QueryInterchainAccount(ctx context.Context, owner, connectionID string) (string, error) {
+	return c.getFullNode().QueryInterchainAccount(ctx, owner, connectionID)
+}
+
+// WaitForInterchainAccount waits until the ICA channel of the interchain account owned by owner over connectionID is open,
+// and returns the host chain address of the account.
+func (c *CosmosChain) WaitForInterchainAccount(ctx context.Context, owner, connectionID string, timeout time.Duration) (string, error) {
+	var address string
+	err := testutil.WaitForCondition(timeout, 2*time.Second, func() (bool, error) {
+		// The query fails until the channel handshake has completed.
+		addr, err := c.QueryInterchainAccount(ctx, owner, connectionID)
+		if err != nil {
+			return false, nil
+		}
+		address = addr
+		return address != "", nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("interchain account of %s over %s was not opened: %w", owner, connectionID, err)
+	}
+	return address, nil
+}
+
+// SendInterchainTx executes msgs on the host chain with the interchain account owned by keyName over connectionID.
+// The messages are packed with the encoding config of the chain, so their types must be registered in it.
+// A relativeTimeout of zero uses the default ICS-27 packet timeout.
+// The returned transaction contains the sent packet, see InterchainTxResult.
+func (c *CosmosChain) SendInterchainTx(
+	ctx context.Context,
+	keyName, connectionID string,
+	msgs []sdk.Msg,
+	memo string,
+	relativeTimeout time.Duration,
+) (ibc.Tx, error) {
+	protoMsgs := make([]proto.Message, len(msgs))
+	for i, msg := range msgs {
+		protoMsgs[i] = msg
+	}
+
+	cdc := codec.NewProtoCodec(c.cfg.EncodingConfig.InterfaceRegistry)
+	data, err := icatypes.SerializeCosmosTx(cdc, protoMsgs)
+	if err != nil {
+		return ibc.Tx{}, fmt.Errorf("failed to serialize interchain tx: %w", err)
+	}
+
+	packetData, err := cdc.MarshalJSON(&icatypes.InterchainAccountPacketData{
+		Type: icatypes.EXECUTE_TX,
+		Data: data,
+		Memo: memo,
+	})
+	if err != nil {
+		return ibc.Tx{}, fmt.Errorf("failed to marshal interchain packet data: %w", err)
+	}
+
+	txHash, err := c.getFullNode().SendInterchainTx(ctx, keyName, connectionID, packetData, relativeTimeout)
+	if err != nil {
+		return ibc.Tx{}, fmt.Errorf("failed to send interchain tx: %w", err)
+	}
+	return c.sentPacketTx(txHash)
+}
+
+// InterchainTxResult waits for the acknowledgement of the interchain tx sent in tx
+// and returns the responses of the messages executed on the host chain.
+// An interchain tx that failed on the host chain returns an error.
+func (c *CosmosChain) InterchainTxResult(ctx context.Context, tx ibc.Tx) (*sdk.TxMsgData, error) {
+	ack, err := testutil.PollForAck(ctx, c, tx.Height, tx.Height+ackPollBlocks, tx.Packet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find acknowledgement of interchain tx: %w", err)
+	}
+	return ParseInterchainTxAcknowledgement(ack.Acknowledgement)
+}
+
+// ParseInterchainTxAcknowledgement decodes the acknowledgement of an interchain tx
+// into the responses of the messages executed on the host chain.
+func ParseInterchainTxAcknowledgement(ack []byte) (*sdk.TxMsgData, error) {
+	var channelAck chanTypes.Acknowledgement
+	if err := chanTypes.SubModuleCdc.UnmarshalJSON(ack, &channelAck); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal acknowledgement: %w", err)
+	}
+	if !channelAck.Success() {
+		return nil, errors.New("interchain tx failed on host chain: " + channelAck.GetError())
+	}
+
+	var txMsgData sdk.TxMsgData
+	if err := proto.Unmarshal(channelAck.GetResult(), &txMsgData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal interchain tx result: %w", err)
+	}
+	return &txMsgData, nil
+}
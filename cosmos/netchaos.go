@@ -0,0 +1,73 @@
+package cosmos
+
+import (
+	"context"
+
+	"github.com/decentrio/rollup-e2e-testing/netchaos"
+)
+
+// ContainerIDs returns the IDs of the containers of the node and its sidecars,
+// so a single node can be targeted by network faults.
+func (node *Node) ContainerIDs() []string {
+	ids := []string{node.ContainerID()}
+	for _, s := range node.Sidecars {
+		if id := s.containerLifecycle.ContainerID(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ContainerIDs returns the IDs of the containers of all the nodes of the chain and their sidecars.
+func (c *CosmosChain) ContainerIDs() []string {
+	var ids []string
+	for _, n := range c.Nodes() {
+		ids = append(ids, n.ContainerIDs()...)
+	}
+	for _, s := range c.Sidecars {
+		if id := s.containerLifecycle.ContainerID(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// NetworkChaos returns the fault injector of the docker network of the chain.
+// The faults injected through the chain methods below are tracked by it, see netchaos.Network.Heal.
+func (c *CosmosChain) NetworkChaos() *netchaos.Network {
+	c.chaosMu.Lock()
+	defer c.chaosMu.Unlock()
+
+	if c.chaos == nil {
+		fn := c.getFullNode()
+		c.chaos = netchaos.NewNetwork(c.log, fn.DockerClient, c.testName, fn.NetworkID)
+	}
+	return c.chaos
+}
+
+// DisconnectNetwork partitions all the nodes of the chain from the docker network,
+// e.g. to halt a hub or cut a rollapp from its DA layer.
+func (c *CosmosChain) DisconnectNetwork(ctx context.Context) error {
+	return c.NetworkChaos().Disconnect(ctx, c)
+}
+
+// ReconnectNetwork reverts DisconnectNetwork.
+func (c *CosmosChain) ReconnectNetwork(ctx context.Context) error {
+	return c.NetworkChaos().Reconnect(ctx, c)
+}
+
+// ImpairNetwork applies latency and packet loss to the traffic sent by all the nodes of the chain.
+// A zero imp removes the impairment.
+func (c *CosmosChain) ImpairNetwork(ctx context.Context, imp netchaos.Impairment) error {
+	return c.NetworkChaos().Impair(ctx, imp, c)
+}
+
+// BlockPeer drops all traffic between the nodes of the chain and peer, e.g. another chain or a relayer.
+func (c *CosmosChain) BlockPeer(ctx context.Context, peer netchaos.Target) error {
+	return c.NetworkChaos().Block(ctx, c, peer)
+}
+
+// UnblockPeer reverts BlockPeer.
+func (c *CosmosChain) UnblockPeer(ctx context.Context, peer netchaos.Target) error {
+	return c.NetworkChaos().Unblock(ctx, c, peer)
+}
@@ -35,7 +35,6 @@ import (
 	"github.com/decentrio/rollup-e2e-testing/ibc"
 	"github.com/decentrio/rollup-e2e-testing/testutil"
 	volumetypes "github.com/docker/docker/api/types/volume"
-	dockerclient "github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 	"go.uber.org/zap"
 	"golang.org/x/exp/rand"
@@ -49,7 +48,7 @@ type Node struct {
 	Chain        ibc.Chain
 	Validator    bool
 	NetworkID    string
-	DockerClient *dockerclient.Client
+	DockerClient dockerutil.Runtime
 	Client       rpcclient.Client
 	TestName     string
 	Image        ibc.DockerImage
@@ -70,14 +69,15 @@ func (node *Node) NewSidecarProcess(
 	ctx context.Context,
 	preStart bool,
 	processName string,
-	cli *dockerclient.Client,
+	cli dockerutil.Runtime,
 	networkID string,
 	image ibc.DockerImage,
 	homeDir string,
 	ports []string,
 	startCmd []string,
+	resources dockerutil.ContainerResources,
 ) error {
-	s := NewSidecar(node.log, true, preStart, node.Chain, cli, networkID, processName, node.TestName, image, homeDir, node.Index, ports, startCmd)
+	s := NewSidecar(node.log, true, preStart, node.Chain, cli, networkID, processName, node.TestName, image, homeDir, node.Index, ports, startCmd, resources)
 	v, err := cli.VolumeCreate(ctx, volumetypes.CreateOptions{
 		Labels: map[string]string{
 			dockerutil.CleanupLabel:   node.TestName,
@@ -102,7 +102,7 @@ func (node *Node) NewSidecarProcess(
 	return nil
 }
 
-func NewNode(log *zap.Logger, validator bool, chain *CosmosChain, dockerClient *dockerclient.Client, networkID string, testName string, image ibc.DockerImage, index int) *Node {
+func NewNode(log *zap.Logger, validator bool, chain *CosmosChain, dockerClient dockerutil.Runtime, networkID string, testName string, image ibc.DockerImage, index int) *Node {
 	node := &Node{
 		log: log,
 
@@ -865,11 +865,24 @@ func (node *Node) SendIBCTransfer(
 	keyName string,
 	toWallet ibc.WalletData,
 	options ibc.TransferOptions,
+) (string, error) {
+	return node.sendIBCTransfer(ctx, channelID, keyName, toWallet, options, "auto")
+}
+
+// sendIBCTransfer sends an ICS-20 transfer with the given gas limit.
+// A fixed limit skips the gas simulation, so a rejected transfer is broadcast and fails with its ABCI code.
+func (node *Node) sendIBCTransfer(
+	ctx context.Context,
+	channelID string,
+	keyName string,
+	toWallet ibc.WalletData,
+	options ibc.TransferOptions,
+	gas string,
 ) (string, error) {
 	command := []string{
 		"ibc-transfer", "transfer", "transfer", channelID,
 		toWallet.Address, fmt.Sprintf("%s%s", toWallet.Amount.String(), toWallet.Denom),
-		"--gas", "auto",
+		"--gas", gas,
 	}
 	if options.Timeout != nil {
 		if options.Timeout.NanoSeconds > 0 {
@@ -1218,6 +1231,51 @@ func (node *Node) QueryEscrowAddress(ctx context.Context, portID, channelID stri
 	return string(bytes.TrimSuffix(stdout, []byte("\n"))), nil
 }
 
+// RegisterInterchainAccount registers an interchain account owned by keyName over connectionID.
+// If version is empty, the host chain fills in the default ICS-27 metadata of the connection.
+func (node *Node) RegisterInterchainAccount(ctx context.Context, keyName, connectionID, version string) (string, error) {
+	command := []string{"interchain-accounts", "controller", "register", connectionID, "--gas", "auto"}
+	if version != "" {
+		command = append(command, "--version", version)
+	}
+	return node.ExecTx(ctx, keyName, command...)
+}
+
+// SendInterchainTx sends the ICS-27 packet data in packetData, as JSON, from the interchain account
+// owned by keyName over connectionID.
+func (node *Node) SendInterchainTx(ctx context.Context, keyName, connectionID string, packetData []byte, relativeTimeout time.Duration) (string, error) {
+	file := "ica_packet_data.json"
+	if err := node.WriteFile(ctx, packetData, file); err != nil {
+		return "", fmt.Errorf("writing ica packet data to docker volume: %w", err)
+	}
+
+	command := []string{
+		"interchain-accounts", "controller", "send-tx", connectionID, path.Join(node.HomeDir(), file),
+		"--gas", "auto",
+	}
+	if relativeTimeout > 0 {
+		command = append(command, "--relative-packet-timeout", strconv.FormatInt(relativeTimeout.Nanoseconds(), 10))
+	}
+	return node.ExecTx(ctx, keyName, command...)
+}
+
+// QueryInterchainAccount returns the address on the host chain of the interchain account
+// owned by owner over connectionID.
+func (node *Node) QueryInterchainAccount(ctx context.Context, owner, connectionID string) (string, error) {
+	stdout, _, err := node.ExecQuery(ctx, "interchain-accounts", "controller", "interchain-account", owner, connectionID)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(stdout, &resp); err != nil {
+		return "", err
+	}
+	return resp.Address, nil
+}
+
 // QueryHubGenesisState query hub genesis state
 func (node *Node) QueryHubGenesisState(ctx context.Context) (HubGenesisState, error) {
 	stdout, _, err := node.ExecQuery(ctx, "hubgenesis", "state")
@@ -1520,7 +1578,7 @@ func (node *Node) CreateNodeContainer(ctx context.Context, command []string) err
 	if chainType[0] == "hub" && chainType[1] == "celes" {
 		cmd = []string{"/bin/bash", "/opt/start.sh", node.HomeDir()}
 	}
-	return node.containerLifecycle.CreateContainer(ctx, node.TestName, node.NetworkID, node.Image, sentryPorts, node.Bind(), node.HostName(), cmd)
+	return node.containerLifecycle.CreateContainer(ctx, node.TestName, node.NetworkID, node.Image, sentryPorts, node.Bind(), node.HostName(), cmd, node.Chain.Config().Resources)
 }
 
 func (node *Node) StartContainer(ctx context.Context) error {
@@ -1541,11 +1599,12 @@ func (node *Node) StartContainer(ctx context.Context) error {
 		return err
 	}
 
-	// Set the host ports once since they will not change after the container has started.
+	// Read the host ports after every start, since the engine may assign new ones, see dockerutil.PortAllocationDocker.
 	hostPorts, err := node.containerLifecycle.GetHostPorts(ctx, rpcPort, grpcPort, apiPort)
 	if err != nil {
 		return err
 	}
+	prev := node.hostAddresses()
 	node.hostRPCPort, node.hostGRPCPort, node.hostAPIPort = hostPorts[0], hostPorts[1], hostPorts[2]
 
 	err = node.NewClient("tcp://" + node.hostRPCPort)
@@ -1554,7 +1613,7 @@ func (node *Node) StartContainer(ctx context.Context) error {
 	}
 
 	time.Sleep(5 * time.Second)
-	return retry.Do(func() error {
+	err = retry.Do(func() error {
 		stat, err := node.Client.Status(ctx)
 		if err != nil {
 			return err
@@ -1565,6 +1624,29 @@ func (node *Node) StartContainer(ctx context.Context) error {
 		}
 		return nil
 	}, retry.Context(ctx), retry.Attempts(40), retry.Delay(3*time.Second), retry.DelayType(retry.FixedDelay))
+	if err != nil {
+		return err
+	}
+
+	// The configs of the relayers running on the host hold the previous host addresses.
+	if cur := node.hostAddresses(); prev.RPC != "" && cur != prev {
+		if n, ok := node.Chain.(interface {
+			notifyHostAddresses(ctx context.Context, old, new ibc.HostAddresses) error
+		}); ok {
+			if err := n.notifyHostAddresses(ctx, prev, cur); err != nil {
+				return fmt.Errorf("failed to update host addresses of node %s: %w", node.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// hostAddresses returns the addresses of the node reachable from the host, empty before its first start.
+func (node *Node) hostAddresses() ibc.HostAddresses {
+	if node.hostRPCPort == "" {
+		return ibc.HostAddresses{}
+	}
+	return ibc.HostAddresses{RPC: "http://" + node.hostRPCPort, GRPC: node.hostGRPCPort, API: "http://" + node.hostAPIPort}
 }
 
 func (node *Node) StopContainer(ctx context.Context) error {
@@ -1585,6 +1667,30 @@ func (node *Node) RemoveContainer(ctx context.Context) error {
 	return node.containerLifecycle.RemoveContainer(ctx)
 }
 
+// Pause freezes the processes of the node container, as if the node hung. Its sidecars keep running.
+func (node *Node) Pause(ctx context.Context) error {
+	return node.containerLifecycle.PauseContainer(ctx)
+}
+
+// Resume unfreezes the node container paused by Pause.
+func (node *Node) Resume(ctx context.Context) error {
+	return node.containerLifecycle.UnpauseContainer(ctx)
+}
+
+// Kill stops the node container abruptly, as in a crash. The container and its volume are kept, see Restart.
+func (node *Node) Kill(ctx context.Context) error {
+	return node.containerLifecycle.KillContainer(ctx)
+}
+
+// Restart stops the node container if it is running and starts it again with the same volume,
+// waiting for the node to catch up.
+func (node *Node) Restart(ctx context.Context) error {
+	if err := node.containerLifecycle.StopContainer(ctx); err != nil {
+		return fmt.Errorf("failed to stop node %s: %w", node.Name(), err)
+	}
+	return node.StartContainer(ctx)
+}
+
 // InitValidatorFiles creates the node files and signs a genesis transaction
 func (node *Node) InitValidatorGenTx(
 	ctx context.Context,
@@ -0,0 +1,186 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+)
+
+var testImage = ibc.DockerImage{Repository: "dymd", Version: "v1", UidGid: "1025:1025"}
+
+func testChain(t *testing.T) *CosmosChain {
+	return NewCosmosChain(t.Name(), ibc.ChainConfig{
+		Type:    "hub-dym",
+		Name:    "dymension",
+		ChainID: "dymension_100-1",
+		Bin:     "dymd",
+		Images:  []ibc.DockerImage{testImage},
+	}, 1, 0, zaptest.NewLogger(t))
+}
+
+// cometStatusServer serves the status of a node at height 5 that caught up, as the RPC of a started node.
+func cometStatusServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":{` +
+			`"node_info":{"protocol_version":{"p2p":"8","block":"11","app":"0"},"network":"dymension_100-1","other":{}},` +
+			`"sync_info":{"latest_block_height":"5","latest_block_time":"2024-01-01T00:00:00Z",` +
+			`"earliest_block_height":"1","earliest_block_time":"2024-01-01T00:00:00Z","catching_up":false},` +
+			`"validator_info":{"voting_power":"0"}}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNodeStartContainer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	srv := cometStatusServer(t)
+	_, srvPort, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	var (
+		mu      sync.Mutex
+		started []string
+	)
+	rt := dockerutil.NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		if len(cmd) > 1 && cmd[1] == "start" {
+			mu.Lock()
+			defer mu.Unlock()
+			started = append(started, cmd[0])
+			return dockerutil.FakeResult{Running: true}
+		}
+		return dockerutil.FakeResult{}
+	}
+	rt.HostPortFunc = func(containerName string, port nat.Port) string {
+		if strings.Contains(containerName, "-val-0-") && port == rpcPort {
+			return srvPort
+		}
+		return ""
+	}
+
+	chain := testChain(t)
+	node := NewNode(zaptest.NewLogger(t), true, chain, rt, "net", t.Name(), testImage, 0)
+	require.NoError(t, node.NewSidecarProcess(ctx, true, "oracle", rt, "net", testImage, "", []string{"8080/tcp"}, []string{"oracle", "start"}, dockerutil.ContainerResources{}))
+	chain.Validators = Nodes{node}
+
+	require.NoError(t, node.CreateNodeContainer(ctx, nil))
+	require.NoError(t, node.StartContainer(ctx))
+
+	// The pre-start sidecar runs before the node.
+	require.Equal(t, []string{"oracle", "dymd"}, started)
+	require.NoError(t, node.Sidecars[0].containerLifecycle.Running(ctx))
+	require.Equal(t, "http://127.0.0.1:"+srvPort, chain.GetHostRPCAddress())
+	require.NotEmpty(t, chain.GetHostGRPCAddress())
+	require.NotNil(t, node.Client)
+
+	cont, err := rt.ContainerInspect(ctx, node.ContainerID())
+	require.NoError(t, err)
+	require.Equal(t, []string{"dymd", "start", "--home", node.HomeDir(), "--x-crisis-skip-assert-invariants"}, []string(cont.Config.Cmd))
+	require.Equal(t, node.HostName(), cont.Config.Hostname)
+
+	require.NoError(t, node.StopContainer(ctx))
+	require.Error(t, node.Sidecars[0].containerLifecycle.Running(ctx))
+	require.NoError(t, node.RemoveContainer(ctx))
+}
+
+func TestNodeRestartNotifiesHostAddresses(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	_, srvPort, err := net.SplitHostPort(cometStatusServer(t).Listener.Addr().String())
+	require.NoError(t, err)
+
+	rt := dockerutil.NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		return dockerutil.FakeResult{Running: len(cmd) > 1 && cmd[1] == "start"}
+	}
+	// The engine assigns new host ports to the other ports on every start, see dockerutil.PortAllocationDocker.
+	rt.HostPortFunc = func(containerName string, port nat.Port) string {
+		if port == rpcPort {
+			return srvPort
+		}
+		return ""
+	}
+
+	chain := testChain(t)
+	node := NewNode(zaptest.NewLogger(t), true, chain, rt, "net", t.Name(), testImage, 0)
+	chain.Validators = Nodes{node}
+
+	var changes [][2]ibc.HostAddresses
+	chain.OnHostAddressesChange(func(ctx context.Context, old, new ibc.HostAddresses) error {
+		changes = append(changes, [2]ibc.HostAddresses{old, new})
+		return nil
+	})
+
+	require.NoError(t, node.CreateNodeContainer(ctx, nil))
+	require.NoError(t, node.StartContainer(ctx))
+	require.Empty(t, changes)
+
+	before := node.hostAddresses()
+	require.NoError(t, node.Restart(ctx))
+	after := node.hostAddresses()
+	require.NotEqual(t, before.GRPC, after.GRPC)
+	require.Equal(t, [][2]ibc.HostAddresses{{before, after}}, changes)
+	require.Equal(t, after.GRPC, chain.GetHostGRPCAddress())
+}
+
+func TestSidecarProcess(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rt := dockerutil.NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		if strings.Join(cmd, " ") == "oracle start" {
+			return dockerutil.FakeResult{Running: true}
+		}
+		return dockerutil.FakeResult{Stdout: []byte(containerName + ": " + strings.Join(cmd, " "))}
+	}
+
+	chain := testChain(t)
+	s := NewSidecar(zaptest.NewLogger(t), false, false, chain, rt, "net", "oracle", t.Name(), testImage, "", 0, []string{"8080/tcp"}, []string{"oracle", "start"}, dockerutil.ContainerResources{})
+	require.Equal(t, "/home/sidecar", s.HomeDir())
+	require.True(t, strings.HasPrefix(s.Name(), "dymension_100-1-oracle-0-"))
+
+	require.NoError(t, s.CreateContainer(ctx))
+	require.NoError(t, s.StartContainer(ctx))
+	require.NoError(t, s.containerLifecycle.Running(ctx))
+
+	ports, err := s.GetHostPorts(ctx, "8080/tcp")
+	require.NoError(t, err)
+	require.NotEmpty(t, ports[0])
+
+	cont, err := rt.ContainerInspect(ctx, s.containerLifecycle.ContainerID())
+	require.NoError(t, err)
+	require.Equal(t, []string{"oracle", "start"}, []string(cont.Config.Cmd))
+	require.Equal(t, s.Bind(), cont.HostConfig.Binds)
+
+	res := s.containerLifecycle.Exec(ctx, []string{"oracle", "status"}, nil)
+	require.NoError(t, res.Err)
+	require.Equal(t, s.Name()+": oracle status", string(res.Stdout))
+
+	require.NoError(t, s.StopContainer(ctx))
+	require.Error(t, s.containerLifecycle.Running(ctx))
+	require.NoError(t, s.RemoveContainer(ctx))
+}
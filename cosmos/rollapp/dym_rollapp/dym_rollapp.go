@@ -375,6 +375,22 @@ func (c *DymRollApp) Configuration(testName string, ctx context.Context, forkRol
 	return nil
 }
 
+// ExportForkGenesis stops the rollapp, exports its state at height and returns
+// a genesis file that starts a fork of the rollapp under forkChainID.
+// The rollapp nodes are left stopped; use StartAllNodes to resume them.
+func (c *DymRollApp) ExportForkGenesis(ctx context.Context, height int64, forkChainID string) ([]byte, error) {
+	if err := c.StopAllNodes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to stop rollapp %s: %w", c.Config().ChainID, err)
+	}
+
+	state, err := c.ExportState(ctx, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export state of rollapp %s: %w", c.Config().ChainID, err)
+	}
+
+	return cosmos.ForkGenesis([]byte(state), forkChainID)
+}
+
 func (c *DymRollApp) ShowSequencer(ctx context.Context) (string, error) {
 	var command []string
 	command = append(command, "dymint", "show-sequencer")
@@ -387,6 +403,27 @@ func (c *DymRollApp) GetSequencer() string {
 	return c.sequencerKey
 }
 
+// RestoreNodes restores the nodes of the rollapp from a network snapshot, see ibc.Snapshotter,
+// and the sequencer key which is not part of the snapshot.
+func (c *DymRollApp) RestoreNodes(ctx context.Context, dir string, nodes []ibc.NodeSnapshot, rewrite func(string) string) error {
+	if err := c.CosmosChain.RestoreNodes(ctx, dir, nodes, rewrite); err != nil {
+		return err
+	}
+
+	c.sequencerKeyDir = c.Validators[0].HomeDir()
+	seq, err := c.ShowSequencer(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to show seq %s: %w", c.Config().Name, err)
+	}
+	c.sequencerKey = seq
+	return nil
+}
+
+// SequencerNode returns the node running the sequencer of the rollapp, e.g. to target it with chaos actions.
+func (c *DymRollApp) SequencerNode() *cosmos.Node {
+	return c.Validators[0]
+}
+
 func (c *DymRollApp) SetGenesisAccount(ctx context.Context, bech32 string) error {
 	// for the validators we need to collect the gentxs and the accounts
 	// to the first node's genesis file
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 
-	dockerclient "github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 	"go.uber.org/zap"
 
@@ -27,12 +26,13 @@ type SidecarProcess struct {
 	ProcessName        string
 	TestName           string
 	VolumeName         string
-	DockerClient       *dockerclient.Client
+	DockerClient       dockerutil.Runtime
 	NetworkID          string
 	Image              ibc.DockerImage
 	ports              nat.PortSet
 	startCmd           []string
 	homeDir            string
+	resources          dockerutil.ContainerResources
 	containerLifecycle *dockerutil.ContainerLifecycle
 }
 
@@ -42,13 +42,14 @@ func NewSidecar(
 	validatorProcess bool,
 	preStart bool,
 	chain ibc.Chain,
-	dockerClient *dockerclient.Client,
+	dockerClient dockerutil.Runtime,
 	networkID, processName, testName string,
 	image ibc.DockerImage,
 	homeDir string,
 	index int,
 	ports []string,
 	startCmd []string,
+	resources dockerutil.ContainerResources,
 ) *SidecarProcess {
 	processPorts := nat.PortSet{}
 	for _, port := range ports {
@@ -71,6 +72,7 @@ func NewSidecar(
 		homeDir:          homeDir,
 		ports:            processPorts,
 		startCmd:         startCmd,
+		resources:        resources,
 	}
 	s.containerLifecycle = dockerutil.NewContainerLifecycle(log, dockerClient, s.Name())
 	return s
@@ -91,7 +93,7 @@ func (s *SidecarProcess) logger() *zap.Logger {
 	)
 }
 func (s *SidecarProcess) CreateContainer(ctx context.Context) error {
-	return s.containerLifecycle.CreateContainer(ctx, s.TestName, s.NetworkID, s.Image, s.ports, s.Bind(), s.HostName(), s.startCmd)
+	return s.containerLifecycle.CreateContainer(ctx, s.TestName, s.NetworkID, s.Image, s.ports, s.Bind(), s.HostName(), s.startCmd, s.resources)
 }
 func (s *SidecarProcess) StartContainer(ctx context.Context) error {
 	return s.containerLifecycle.StartContainer(ctx)
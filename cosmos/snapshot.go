@@ -0,0 +1,230 @@
+package cosmos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+)
+
+var _ ibc.Snapshotter = (*CosmosChain)(nil)
+
+// StopNodes implements ibc.Snapshotter. The sidecars keep running.
+func (c *CosmosChain) StopNodes(ctx context.Context) error {
+	var eg errgroup.Group
+	for _, n := range c.Nodes() {
+		n := n
+		eg.Go(func() error {
+			if err := n.containerLifecycle.StopContainer(ctx); err != nil {
+				return fmt.Errorf("failed to stop node %s: %w", n.Name(), err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// StartNodes implements ibc.Snapshotter.
+func (c *CosmosChain) StartNodes(ctx context.Context) error {
+	var eg errgroup.Group
+	for _, n := range c.Nodes() {
+		n := n
+		eg.Go(func() error {
+			if err := n.StartContainer(ctx); err != nil {
+				return fmt.Errorf("failed to start node %s: %w", n.Name(), err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// SnapshotNodes implements ibc.Snapshotter. Sidecars are not archived, they are recreated on restore.
+func (c *CosmosChain) SnapshotNodes(ctx context.Context, dir string) ([]ibc.NodeSnapshot, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	nodes := c.Nodes()
+	snapshots := make([]ibc.NodeSnapshot, len(nodes))
+	var eg errgroup.Group
+	for i, n := range nodes {
+		i, n := i, n
+		eg.Go(func() error {
+			s, err := n.snapshot(ctx, dir)
+			if err != nil {
+				return fmt.Errorf("failed to snapshot node %s: %w", n.Name(), err)
+			}
+			snapshots[i] = s
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// DescribeNodes implements ibc.Snapshotter.
+func (c *CosmosChain) DescribeNodes() []ibc.NodeSnapshot {
+	nodes := c.Nodes()
+	snapshots := make([]ibc.NodeSnapshot, len(nodes))
+	for i, n := range nodes {
+		snapshots[i] = ibc.NodeSnapshot{
+			Validator: n.Validator,
+			Index:     n.Index,
+			HostName:  n.HostName(),
+			HomeDir:   n.HomeDir(),
+		}
+	}
+	return snapshots
+}
+
+// RestoreNodes implements ibc.Snapshotter.
+func (c *CosmosChain) RestoreNodes(ctx context.Context, dir string, snapshots []ibc.NodeSnapshot, rewrite func(string) string) error {
+	nodes := c.Nodes()
+	if len(nodes) != len(snapshots) {
+		return fmt.Errorf("chain %s has %d nodes but its snapshot has %d", c.cfg.ChainID, len(nodes), len(snapshots))
+	}
+
+	var eg errgroup.Group
+	for _, n := range nodes {
+		n := n
+		s, ok := findNodeSnapshot(snapshots, n.Validator, n.Index)
+		if !ok {
+			return fmt.Errorf("node %s is not in the snapshot of chain %s", n.Name(), c.cfg.ChainID)
+		}
+		eg.Go(func() error {
+			if err := n.restore(ctx, dir, s, rewrite); err != nil {
+				return fmt.Errorf("failed to restore node %s: %w", n.Name(), err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+func findNodeSnapshot(snapshots []ibc.NodeSnapshot, validator bool, index int) (ibc.NodeSnapshot, bool) {
+	for _, s := range snapshots {
+		if s.Validator == validator && s.Index == index {
+			return s, true
+		}
+	}
+	return ibc.NodeSnapshot{}, false
+}
+
+// snapshotHomeDir is the directory holding the node data while it runs.
+func (node *Node) snapshotHomeDir() string {
+	if node.Chain.Config().NoHostMount {
+		// The start command copies the home directory, see CreateNodeContainer.
+		return node.HomeDir() + "_nomnt"
+	}
+	return node.HomeDir()
+}
+
+// snapshot archives the home directory of the stopped node into dir.
+func (node *Node) snapshot(ctx context.Context, dir string) (ibc.NodeSnapshot, error) {
+	s := ibc.NodeSnapshot{
+		Validator: node.Validator,
+		Index:     node.Index,
+		HostName:  node.HostName(),
+		HomeDir:   node.HomeDir(),
+	}
+	nodeType := "fn"
+	if node.Validator {
+		nodeType = "val"
+	}
+	s.Archive = fmt.Sprintf("%s-%d.tar", nodeType, node.Index)
+
+	inspect, err := node.DockerClient.ContainerInspect(ctx, node.ContainerID())
+	if err != nil {
+		return s, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	s.Cmd = inspect.Config.Cmd
+	if inspect.State != nil && inspect.State.Running {
+		return s, errors.New("node is running")
+	}
+
+	rc, _, err := node.DockerClient.CopyFromContainer(ctx, node.ContainerID(), node.snapshotHomeDir())
+	if err != nil {
+		return s, fmt.Errorf("failed to copy home directory: %w", err)
+	}
+	defer rc.Close()
+
+	f, err := os.Create(filepath.Join(dir, s.Archive))
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, rc); err != nil {
+		return s, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return s, f.Close()
+}
+
+// restore creates the node container from the snapshot s archived in dir, and starts it.
+func (node *Node) restore(ctx context.Context, dir string, s ibc.NodeSnapshot, rewrite func(string) string) error {
+	cmd := make([]string, len(s.Cmd))
+	for i, arg := range s.Cmd {
+		cmd[i] = rewrite(arg)
+	}
+	if err := node.containerLifecycle.CreateContainer(ctx, node.TestName, node.NetworkID, node.Image, sentryPorts, node.Bind(), node.HostName(), cmd, node.Chain.Config().Resources); err != nil {
+		return err
+	}
+
+	f, err := os.Open(filepath.Join(dir, s.Archive))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The archive holds the home directory of the snapshot node, extracted as the home directory of this node.
+	home := path.Base(node.HomeDir())
+	rename := func(name string) string {
+		if i := strings.Index(name, "/"); i >= 0 {
+			return home + name[i:]
+		}
+		return home
+	}
+	rewriteConfig := func(name string, content []byte) []byte {
+		if !isSnapshotConfigFile(name) {
+			return content
+		}
+		return []byte(rewrite(string(content)))
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(dockerutil.RewriteTar(pw, f, rename, rewriteConfig))
+	}()
+	defer pr.Close()
+
+	if err := node.DockerClient.CopyToContainer(ctx, node.ContainerID(), path.Dir(node.HomeDir()), pr, dockertypes.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy home directory: %w", err)
+	}
+
+	return node.StartContainer(ctx)
+}
+
+// isSnapshotConfigFile reports whether the archived file name may refer to hosts or home directories.
+// The genesis file is left untouched as it is large and does not.
+func isSnapshotConfigFile(name string) bool {
+	if !strings.Contains(name, "/config/") || path.Base(name) == "genesis.json" {
+		return false
+	}
+	switch path.Ext(name) {
+	case ".toml", ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
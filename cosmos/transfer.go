@@ -0,0 +1,121 @@
+package cosmos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	transfertypes "github.com/cosmos/ibc-go/v7/modules/apps/transfer/types"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/testutil"
+)
+
+// ackPollBlocks is the number of blocks to wait for the acknowledgement of a transfer.
+const ackPollBlocks = 20
+
+// BalanceDiff is a balance that does not match the expected value after a transfer.
+type BalanceDiff struct {
+	Chain    string
+	Account  string
+	Denom    string
+	Expected sdkmath.Int
+	Actual   sdkmath.Int
+}
+
+func (d BalanceDiff) String() string {
+	return fmt.Sprintf("%s %s %s: expected %s, got %s (diff %s)",
+		d.Chain, d.Account, d.Denom, d.Expected, d.Actual, d.Actual.Sub(d.Expected))
+}
+
+// TransferDiffError is returned by AssertIBCTransfer when one or more balances do not match.
+type TransferDiffError struct {
+	Diffs []BalanceDiff
+}
+
+func (e *TransferDiffError) Error() string {
+	lines := make([]string, len(e.Diffs))
+	for i, d := range e.Diffs {
+		lines[i] = d.String()
+	}
+	return "unexpected balances after ibc transfer:\n" + strings.Join(lines, "\n")
+}
+
+// IBCDenom returns the ibc/<hash> denom of baseDenom once it is received over the given port and channel.
+func IBCDenom(portID, channelID, baseDenom string) string {
+	prefixed := transfertypes.GetPrefixedDenom(portID, channelID, baseDenom)
+	return transfertypes.ParseDenomTrace(prefixed).IBCDenom()
+}
+
+// AssertIBCTransfer sends amount of the native denom of src from sender to receiver on dst over channel,
+// waits for the packet to be acknowledged and checks the sender was debited by the amount plus gas fees,
+// the receiver was credited with the ibc denom and the channel escrow account holds the amount.
+// A relayer must be relaying the channel. Mismatching balances are reported as a *TransferDiffError.
+func AssertIBCTransfer(
+	ctx context.Context,
+	src, dst *CosmosChain,
+	channel *ibc.ChannelOutput,
+	sender, receiver string,
+	amount sdkmath.Int,
+	options ibc.TransferOptions,
+) (ibc.Tx, error) {
+	denom := src.Config().Denom
+	ibcDenom := IBCDenom(channel.Counterparty.PortID, channel.Counterparty.ChannelID, denom)
+
+	escrow, err := src.getFullNode().QueryEscrowAddress(ctx, channel.PortID, channel.ChannelID)
+	if err != nil {
+		return ibc.Tx{}, fmt.Errorf("failed to query escrow address: %w", err)
+	}
+
+	senderBal, err := src.GetBalance(ctx, sender, denom)
+	if err != nil {
+		return ibc.Tx{}, err
+	}
+	receiverBal, err := dst.GetBalance(ctx, receiver, ibcDenom)
+	if err != nil {
+		return ibc.Tx{}, err
+	}
+	escrowBal, err := src.GetBalance(ctx, escrow, denom)
+	if err != nil {
+		return ibc.Tx{}, err
+	}
+
+	tx, err := src.SendIBCTransfer(ctx, channel.ChannelID, sender, ibc.WalletData{
+		Address: receiver,
+		Denom:   denom,
+		Amount:  amount,
+	}, options)
+	if err != nil {
+		return tx, err
+	}
+	if err := tx.Validate(); err != nil {
+		return tx, fmt.Errorf("ibc transfer tx is invalid: %w", err)
+	}
+
+	if _, err := testutil.PollForAck(ctx, src, tx.Height, tx.Height+ackPollBlocks, tx.Packet); err != nil {
+		return tx, fmt.Errorf("failed to find acknowledgement of ibc transfer: %w", err)
+	}
+
+	fees := sdkmath.NewInt(src.GetGasFeesInNativeDenom(tx.GasSpent))
+	expected := []BalanceDiff{
+		{Chain: src.Config().ChainID, Account: sender, Denom: denom, Expected: senderBal.Sub(amount).Sub(fees)},
+		{Chain: dst.Config().ChainID, Account: receiver, Denom: ibcDenom, Expected: receiverBal.Add(amount)},
+		{Chain: src.Config().ChainID, Account: escrow, Denom: denom, Expected: escrowBal.Add(amount)},
+	}
+	chains := []*CosmosChain{src, dst, src}
+
+	var diffs []BalanceDiff
+	for i, d := range expected {
+		d.Actual, err = chains[i].GetBalance(ctx, d.Account, d.Denom)
+		if err != nil {
+			return tx, err
+		}
+		if !d.Actual.Equal(d.Expected) {
+			diffs = append(diffs, d)
+		}
+	}
+	if len(diffs) > 0 {
+		return tx, &TransferDiffError{Diffs: diffs}
+	}
+	return tx, nil
+}
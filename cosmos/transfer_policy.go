@@ -0,0 +1,238 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	paramsutils "github.com/cosmos/cosmos-sdk/x/params/client/utils"
+	chanTypes "github.com/cosmos/ibc-go/v7/modules/core/04-channel/types"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/testutil"
+)
+
+const (
+	// transferPolicyProposalBlocks is the number of blocks to wait for a transfer policy proposal to pass.
+	transferPolicyProposalBlocks = 20
+
+	// transferCaseGas is the gas limit of the transfers sent by RunTransferCases.
+	transferCaseGas = "300000"
+)
+
+// txCodeRegexp matches the ABCI code in the errors of ExecTx, "transaction failed with code <code>: <raw log>",
+// and in the JSON tx response a failed command may print, but not the exit code of the command.
+var txCodeRegexp = regexp.MustCompile(`(?:\btransaction failed with code |"code":\s*)(\d+)`)
+
+// SetIbcTransferParams enables or disables sending and receiving ICS-20 transfers on c
+// with a param change proposal submitted by keyName and voted by all validators,
+// and waits for the params to be in effect.
+func (c *CosmosChain) SetIbcTransferParams(ctx context.Context, keyName, deposit string, params Params) error {
+	height, err := c.Height(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain height: %w", err)
+	}
+
+	tx, err := c.ParamChangeProposal(ctx, keyName, &paramsutils.ParamChangeProposalJSON{
+		Title:       "Update ibc transfer params",
+		Description: fmt.Sprintf("Set send enabled to %t and receive enabled to %t", params.SendEnabled, params.ReceiveEnabled),
+		Changes: paramsutils.ParamChangesJSON{
+			paramsutils.NewParamChangeJSON("transfer", "SendEnabled", json.RawMessage(strconv.FormatBool(params.SendEnabled))),
+			paramsutils.NewParamChangeJSON("transfer", "ReceiveEnabled", json.RawMessage(strconv.FormatBool(params.ReceiveEnabled))),
+		},
+		Deposit: deposit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.passProposal(ctx, tx.ProposalID, height); err != nil {
+		return err
+	}
+
+	got, err := c.getFullNode().QueryIbcTransferParams(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to query ibc transfer params: %w", err)
+	}
+	if *got != params {
+		return fmt.Errorf("ibc transfer params on %s are %+v, expected %+v", c.cfg.ChainID, *got, params)
+	}
+	return nil
+}
+
+// RegisterIBCDenoms registers metadata of IBC denoms on c with a register-coin proposal
+// submitted by keyName and voted by all validators, and waits for the proposal to pass.
+// The base of each metadata is the ibc/<hash> denom, see IBCDenom.
+func (c *CosmosChain) RegisterIBCDenoms(ctx context.Context, keyName, deposit string, metadata ...DenomMetadata) error {
+	content, err := json.Marshal(struct {
+		Metadata []DenomMetadata `json:"metadata"`
+	}{Metadata: metadata})
+	if err != nil {
+		return fmt.Errorf("failed to marshal denom metadata: %w", err)
+	}
+
+	node := c.getFullNode()
+	const file = "register_ibc_denoms.json"
+	if err := node.WriteFile(ctx, content, file); err != nil {
+		return fmt.Errorf("failed to write denom metadata: %w", err)
+	}
+
+	height, err := c.Height(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain height: %w", err)
+	}
+
+	txHash, err := node.RegisterIBCTokenDenomProposal(ctx, keyName, deposit, path.Join(node.HomeDir(), file))
+	if err != nil {
+		return fmt.Errorf("failed to submit register coin proposal: %w", err)
+	}
+	tx, err := c.txProposal(txHash)
+	if err != nil {
+		return err
+	}
+
+	return c.passProposal(ctx, tx.ProposalID, height)
+}
+
+// passProposal votes yes on proposalID with all validators and waits for it to pass.
+func (c *CosmosChain) passProposal(ctx context.Context, proposalID string, height int64) error {
+	if err := c.VoteOnProposalAllValidators(ctx, proposalID, ProposalVoteYes); err != nil {
+		return fmt.Errorf("failed to vote on proposal %s: %w", proposalID, err)
+	}
+	if _, err := PollForProposalStatus(ctx, c, height, height+transferPolicyProposalBlocks, proposalID, ProposalStatusPassed); err != nil {
+		return fmt.Errorf("proposal %s did not pass: %w", proposalID, err)
+	}
+	return nil
+}
+
+// TransferCase is an ICS-20 transfer sent by RunTransferCases, and its expected outcome.
+type TransferCase struct {
+	Name      string
+	ChannelID string
+	KeyName   string
+	Amount    ibc.WalletData
+	Options   ibc.TransferOptions
+
+	// ExpectedCode is the ABCI code the transfer fails with on the sending chain,
+	// e.g. transfertypes.ErrSendDisabled.ABCICode(). Zero expects the packet to be sent.
+	ExpectedCode uint32
+
+	// ExpectedAckCode is the ABCI code of the error acknowledgement of the packet,
+	// e.g. transfertypes.ErrReceiveDisabled.ABCICode() when the counterparty does not receive transfers.
+	// Zero expects a successful acknowledgement.
+	ExpectedAckCode uint32
+}
+
+// TransferCaseResult is the outcome of a TransferCase.
+type TransferCaseResult struct {
+	Name string
+	Tx   ibc.Tx
+
+	Code    uint32
+	AckCode uint32
+	Err     error
+}
+
+// TransferCasesError is returned by RunTransferCases when one or more transfers had an unexpected outcome.
+type TransferCasesError struct {
+	Failures []string
+}
+
+func (e *TransferCasesError) Error() string {
+	return "unexpected ibc transfer outcomes:\n" + strings.Join(e.Failures, "\n")
+}
+
+// RunTransferCases sends the transfer of every case from c in order and checks it fails with the expected code,
+// or is acknowledged with the expected code by the counterparty. A relayer must be relaying the channels of the cases.
+// Transfers are sent with a fixed gas limit, so transfers rejected by the transfer module are broadcast with their code.
+// Unexpected outcomes are reported as a *TransferCasesError, along with the results of all cases.
+func (c *CosmosChain) RunTransferCases(ctx context.Context, cases []TransferCase) ([]TransferCaseResult, error) {
+	results := make([]TransferCaseResult, len(cases))
+	var failures []string
+	for i, tc := range cases {
+		res := c.runTransferCase(ctx, tc)
+		results[i] = res
+
+		switch {
+		case res.Code != tc.ExpectedCode:
+			failures = append(failures, fmt.Sprintf("%s: expected code %d, got %d (%v)", tc.Name, tc.ExpectedCode, res.Code, res.Err))
+		case res.Code == 0 && res.Err != nil:
+			failures = append(failures, fmt.Sprintf("%s: %v", tc.Name, res.Err))
+		case res.Code == 0 && res.AckCode != tc.ExpectedAckCode:
+			failures = append(failures, fmt.Sprintf("%s: expected ack code %d, got %d", tc.Name, tc.ExpectedAckCode, res.AckCode))
+		}
+	}
+	if len(failures) > 0 {
+		return results, &TransferCasesError{Failures: failures}
+	}
+	return results, nil
+}
+
+func (c *CosmosChain) runTransferCase(ctx context.Context, tc TransferCase) TransferCaseResult {
+	res := TransferCaseResult{Name: tc.Name}
+
+	txHash, err := c.getFullNode().sendIBCTransfer(ctx, tc.ChannelID, tc.KeyName, tc.Amount, tc.Options, transferCaseGas)
+	if err != nil {
+		// Transactions rejected by CheckTx are not included in a block.
+		res.Code, res.Err = txErrorCode(err), err
+		return res
+	}
+
+	txResp, err := c.GetTransaction(txHash)
+	if err != nil {
+		res.Err = fmt.Errorf("failed to get transaction %s: %w", txHash, err)
+		return res
+	}
+	if txResp.Code != 0 {
+		res.Code = txResp.Code
+		res.Err = fmt.Errorf("error in transaction (code: %d): %s", txResp.Code, txResp.RawLog)
+		return res
+	}
+
+	res.Tx, res.Err = c.sentPacketTx(txHash)
+	if res.Err != nil {
+		return res
+	}
+
+	ack, err := testutil.PollForAck(ctx, c, res.Tx.Height, res.Tx.Height+ackPollBlocks, res.Tx.Packet)
+	if err != nil {
+		res.Err = fmt.Errorf("failed to find acknowledgement of ibc transfer: %w", err)
+		return res
+	}
+	res.AckCode, res.Err = ackErrorCode(ack.Acknowledgement)
+	return res
+}
+
+// txErrorCode returns the ABCI code reported in err, or 1 when err has none, e.g. when the command failed.
+func txErrorCode(err error) uint32 {
+	m := txCodeRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 1
+	}
+	code, perr := strconv.ParseUint(m[1], 10, 32)
+	if perr != nil || code == 0 {
+		return 1
+	}
+	return uint32(code)
+}
+
+// ackErrorCode returns the ABCI code of an error acknowledgement, or zero for a successful one.
+func ackErrorCode(ack []byte) (uint32, error) {
+	var channelAck chanTypes.Acknowledgement
+	if err := chanTypes.SubModuleCdc.UnmarshalJSON(ack, &channelAck); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal acknowledgement: %w", err)
+	}
+	if channelAck.Success() {
+		return 0, nil
+	}
+
+	// Error acknowledgements are "ABCI code: <code>: error handling packet: see events for details".
+	var code uint32
+	if _, err := fmt.Sscanf(channelAck.GetError(), "ABCI code: %d", &code); err != nil {
+		return 0, fmt.Errorf("failed to parse error acknowledgement %q: %w", channelAck.GetError(), err)
+	}
+	return code, nil
+}
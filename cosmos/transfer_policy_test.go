@@ -0,0 +1,26 @@
+package cosmos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxErrorCode(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		err  string
+		want uint32
+	}{
+		{"transaction failed with code 5: insufficient funds", 5},
+		{`exit code 1: {"height":"0","txhash":"0A0B","code":13,"raw_log":"insufficient fee"} `, 13},
+		// The exit code of the command is not an ABCI code.
+		{"exit code 2: Error: unknown flag: --packet-memo", 1},
+		{"exit code 1:  Error: rpc error: code = Unknown desc = account not found", 1},
+		{"transaction failed with code 0: ", 1},
+	} {
+		require.Equal(t, tc.want, txErrorCode(errors.New(tc.err)), tc.err)
+	}
+}
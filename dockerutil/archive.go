@@ -0,0 +1,109 @@
+package dockerutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// TarDir writes the files of dir to w as a tar archive, with names relative to dir.
+func TarDir(w io.Writer, dir string) error {
+	return tarBuildContext(w, dir, nil)
+}
+
+// RewriteTar copies the tar archive r to w, passing the name of every entry through rename,
+// and the content of every regular file through rewrite. Both may be nil.
+// Rewrite receives the renamed name and returns the new content, or the content unchanged.
+func RewriteTar(w io.Writer, r io.Reader, rename func(name string) string, rewrite func(name string, content []byte) []byte) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if rename != nil {
+			hdr.Name = rename(hdr.Name)
+		}
+
+		if hdr.Typeflag != tar.TypeReg || rewrite == nil {
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from archive: %w", hdr.Name, err)
+		}
+		content = rewrite(hdr.Name, content)
+		hdr.Size = int64(len(content))
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// UntarDir extracts the tar archive r into dir.
+func UntarDir(dir string, r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid name %q in archive", hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, hdr.FileInfo().Mode().Perm()|0o700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			var content bytes.Buffer
+			if _, err := io.Copy(&content, tr); err != nil {
+				return err
+			}
+			if err := os.WriteFile(target, content.Bytes(), hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package dockerutil
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "config"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "config", "config.yaml"), []byte("rpc-addr: http://val-0:26657"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "key"), []byte("val-0"), 0o600))
+
+	var archive, rewritten bytes.Buffer
+	require.NoError(t, TarDir(&archive, src))
+	require.NoError(t, RewriteTar(&rewritten, &archive, nil, func(name string, content []byte) []byte {
+		if filepath.Ext(name) != ".yaml" {
+			return content
+		}
+		return []byte(strings.ReplaceAll(string(content), "val-0", "val-1"))
+	}))
+
+	dst := t.TempDir()
+	require.NoError(t, UntarDir(dst, &rewritten))
+
+	config, err := os.ReadFile(filepath.Join(dst, "config", "config.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "rpc-addr: http://val-1:26657", string(config))
+
+	key, err := os.ReadFile(filepath.Join(dst, "key"))
+	require.NoError(t, err)
+	require.Equal(t, "val-0", string(key))
+}
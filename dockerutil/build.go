@@ -0,0 +1,271 @@
+package dockerutil
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"go.uber.org/zap"
+)
+
+// ImageBuild describes how to build an image from a Dockerfile, e.g. of a source checkout.
+type ImageBuild struct {
+	// Context is the directory sent to the builder.
+	Context string `yaml:"context"`
+	// Dockerfile is the path of the Dockerfile relative to Context. Defaults to "Dockerfile".
+	Dockerfile string `yaml:"dockerfile"`
+	// Args are the values of the ARG instructions of the Dockerfile.
+	Args map[string]string `yaml:"args"`
+}
+
+// BuildImage builds the image ref from b, unless ref was already built from the same inputs.
+//
+// Built images are cached by the hash of the files of the build context, the Dockerfile and the build args,
+// so editing a source checkout rebuilds the image while rerunning a test does not.
+// The .dockerignore file of the context is honored as by docker build.
+func BuildImage(ctx context.Context, log *zap.Logger, rt Runtime, ref string, b ImageBuild) error {
+	if b.Context == "" {
+		return errors.New("image build context cannot be empty")
+	}
+	dockerfile := b.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	ignore, err := readDockerignore(b.Context, dockerfile)
+	if err != nil {
+		return err
+	}
+
+	hash, err := buildHash(b.Context, dockerfile, b.Args, ignore)
+	if err != nil {
+		return fmt.Errorf("failed to hash build context %s: %w", b.Context, err)
+	}
+
+	if inspect, _, err := rt.ImageInspectWithRaw(ctx, ref); err == nil && inspect.Config != nil && inspect.Config.Labels[BuildHashLabel] == hash {
+		log.Info("Image is up to date", zap.String("image", ref), zap.String("build_hash", hash))
+		return nil
+	}
+
+	log.Info("Building image", zap.String("image", ref), zap.String("context", b.Context), zap.String("dockerfile", dockerfile))
+
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(tarBuildContext(pw, b.Context, ignore))
+	}()
+	defer pr.Close()
+
+	args := make(map[string]*string, len(b.Args))
+	for k, v := range b.Args {
+		v := v
+		args[k] = &v
+	}
+	res, err := rt.ImageBuild(ctx, pr, types.ImageBuildOptions{
+		Tags:        []string{ref},
+		Dockerfile:  filepath.ToSlash(dockerfile),
+		BuildArgs:   args,
+		Labels:      map[string]string{BuildHashLabel: hash},
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build image %s: %w", ref, err)
+	}
+	defer res.Body.Close()
+
+	if err := readJSONMessages(log, res.Body); err != nil {
+		return fmt.Errorf("failed to build image %s: %w", ref, err)
+	}
+	return nil
+}
+
+// readJSONMessages reads the JSON message stream of a build or a pull,
+// logs the build output and returns the error reported in the stream, if any.
+func readJSONMessages(log *zap.Logger, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		var msg struct {
+			Stream      string `json:"stream"`
+			Error       string `json:"error"`
+			ErrorDetail struct {
+				Message string `json:"message"`
+			} `json:"errorDetail"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read output: %w", err)
+		}
+		if msg.ErrorDetail.Message != "" {
+			return errors.New(msg.ErrorDetail.Message)
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+		if line := strings.TrimSpace(msg.Stream); line != "" {
+			log.Debug(line)
+		}
+	}
+}
+
+// readDockerignore returns the matcher of the .dockerignore file of dir, or nil if there is none.
+// As with docker build, the Dockerfile and the .dockerignore file are sent to the builder even if ignored.
+func readDockerignore(dir, dockerfile string) (*patternmatcher.PatternMatcher, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read .dockerignore: %w", err)
+	}
+	defer f.Close()
+
+	patterns, err := ignorefile.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .dockerignore: %w", err)
+	}
+	patterns = append(patterns, "!.dockerignore", "!"+path.Clean(filepath.ToSlash(dockerfile)))
+
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid .dockerignore: %w", err)
+	}
+	return pm, nil
+}
+
+// walkBuildContext calls fn for every file of dir that is not ignored, in lexical order.
+// The files of an ignored directory are still walked if the patterns have exceptions.
+func walkBuildContext(dir string, ignore *patternmatcher.PatternMatcher, fn func(rel, name string, info fs.FileInfo) error) error {
+	parents := map[string]patternmatcher.MatchInfo{}
+	return filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if ignore != nil {
+			skip, info, err := ignore.MatchesUsingParentResults(rel, parents[path.Dir(rel)])
+			if err != nil {
+				return fmt.Errorf("failed to match %s against .dockerignore: %w", rel, err)
+			}
+			if d.IsDir() {
+				parents[rel] = info
+			}
+			if skip {
+				if d.IsDir() && !ignore.Exclusions() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(rel, name, info)
+	})
+}
+
+func buildHash(dir, dockerfile string, args map[string]string, ignore *patternmatcher.PatternMatcher) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "dockerfile %s\n", filepath.ToSlash(dockerfile))
+
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "arg %s=%s\n", k, args[k])
+	}
+
+	err := walkBuildContext(dir, ignore, func(rel, name string, info fs.FileInfo) error {
+		fmt.Fprintf(h, "file %s %s\n", rel, info.Mode())
+		if !info.Mode().IsRegular() {
+			if info.Mode()&fs.ModeSymlink != 0 {
+				target, err := os.Readlink(name)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(h, "link %s\n", target)
+			}
+			return nil
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func tarBuildContext(w io.Writer, dir string, ignore *patternmatcher.PatternMatcher) error {
+	tw := tar.NewWriter(w)
+	err := walkBuildContext(dir, ignore, func(rel, name string, info fs.FileInfo) error {
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(name)
+			if err != nil {
+				return err
+			}
+			link = target
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			// Sockets, devices and pipes cannot be sent to the builder.
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
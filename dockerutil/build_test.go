@@ -0,0 +1,89 @@
+package dockerutil
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBuildImage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rt := NewFakeRuntime()
+	log := zaptest.NewLogger(t)
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	writeFile("gm.Dockerfile", "FROM alpine")
+	writeFile("main.go", "package main")
+	writeFile(".dockerignore", "# build output\nbuild\n")
+
+	b := ImageBuild{Context: dir, Dockerfile: "gm.Dockerfile", Args: map[string]string{"UID": "1000"}}
+	require.NoError(t, BuildImage(ctx, log, rt, "gm:local", b))
+
+	inspect, _, err := rt.ImageInspectWithRaw(ctx, "gm:local")
+	require.NoError(t, err)
+	hash := inspect.Config.Labels[BuildHashLabel]
+	require.NotEmpty(t, hash)
+
+	ignore, err := readDockerignore(dir, b.Dockerfile)
+	require.NoError(t, err)
+
+	// Ignored files do not change the hash.
+	writeFile("build/gmd", "binary")
+	h, err := buildHash(dir, b.Dockerfile, b.Args, ignore)
+	require.NoError(t, err)
+	require.Equal(t, hash, h)
+
+	// Sources and build args do.
+	writeFile("main.go", "package main\n\nfunc main() {}")
+	h, err = buildHash(dir, b.Dockerfile, b.Args, ignore)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, h)
+
+	h2, err := buildHash(dir, b.Dockerfile, map[string]string{"UID": "10001"}, ignore)
+	require.NoError(t, err)
+	require.NotEqual(t, h, h2)
+
+	err = BuildImage(ctx, log, rt, "gm:missing", ImageBuild{Context: dir})
+	require.ErrorContains(t, err, "Cannot locate specified Dockerfile")
+}
+
+func TestDockerignore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".dockerignore":         "*.Dockerfile\n.dockerignore\nbuild\n!build/keep\n**/*.log\n",
+		"gm.Dockerfile":         "FROM alpine",
+		"other.Dockerfile":      "FROM alpine",
+		"main.go":               "package main",
+		"build/gmd":             "binary",
+		"build/keep/config.yml": "keep",
+		"x/y/debug.log":         "log",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	ignore, err := readDockerignore(dir, "gm.Dockerfile")
+	require.NoError(t, err)
+
+	var files []string
+	require.NoError(t, walkBuildContext(dir, ignore, func(rel, name string, info fs.FileInfo) error {
+		if !info.IsDir() {
+			files = append(files, rel)
+		}
+		return nil
+	}))
+	require.Equal(t, []string{".dockerignore", "build/keep/config.yml", "gm.Dockerfile", "main.go"}, files)
+}
@@ -0,0 +1,117 @@
+package dockerutil
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// FailureBundleDir is the directory DockerSetup writes a zip of debugging artifacts to
+// when a test fails, named after the test. No bundle is written if it is empty.
+//
+// The value is empty by default, but can be initialized by setting the
+// environment variable IBCTEST_FAILURE_BUNDLE_DIR.
+// Alternatively, importers of the dockerutil package may set the variable,
+// the public API being rollupe2etesting.WriteFailureBundles(dir).
+var FailureBundleDir = os.Getenv("IBCTEST_FAILURE_BUNDLE_DIR")
+
+// FailureBundleStagingDir returns the directory collecting the artifacts of the failure bundle of testName,
+// or an empty string if FailureBundleDir is empty.
+// The directory is zipped and removed by the cleanup of DockerSetup, after the full logs of the containers are added.
+func FailureBundleStagingDir(testName string) string {
+	if FailureBundleDir == "" {
+		return ""
+	}
+	return filepath.Join(FailureBundleDir, SanitizeContainerName(testName))
+}
+
+// writeContainerLogs writes the full logs of c to dir/logs.
+func writeContainerLogs(ctx context.Context, cli Runtime, dir string, c types.Container) error {
+	name := c.ID
+	if len(c.Names) > 0 {
+		name = strings.TrimPrefix(c.Names[0], "/")
+	}
+
+	rc, err := cli.ContainerLogs(ctx, c.ID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get logs of container %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	dir = filepath.Join(dir, "logs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, name+".log"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Logs are multiplexed into one stream; see docs for ContainerLogs.
+	if _, err := stdcopy.StdCopy(f, f, rc); err != nil {
+		return fmt.Errorf("failed to write logs of container %s: %w", name, err)
+	}
+	return f.Close()
+}
+
+// zipFailureBundle zips the staging directory of the failure bundle of testName
+// into FailureBundleDir and removes it. It returns the path of the zip.
+func zipFailureBundle(testName string) (string, error) {
+	dir := FailureBundleStagingDir(testName)
+	file := dir + ".zip"
+
+	f, err := os.Create(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := zipDir(f, dir); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return file, os.RemoveAll(dir)
+}
+
+// zipDir writes the regular files of dir to w as a zip archive, with names relative to dir.
+func zipDir(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		fw, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(fw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,41 @@
+package dockerutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "logs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logs", "val-0.log"), []byte("committed state"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte("{}\n"), 0o644))
+
+	var buf bytes.Buffer
+	require.NoError(t, zipDir(&buf, dir))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = string(content)
+	}
+	require.Equal(t, map[string]string{
+		"logs/val-0.log": "committed state",
+		"report.json":    "{}\n",
+	}, files)
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/client"
 )
 
 // Allow multiple goroutines to check for busybox
@@ -21,9 +20,10 @@ var (
 	hasBusybox      bool
 )
 
-const busyboxRef = "busybox:stable"
+// BusyboxRef is the image used to read, write and chown files of volumes.
+const BusyboxRef = "busybox:stable"
 
-func ensureBusybox(ctx context.Context, cli *client.Client) error {
+func ensureBusybox(ctx context.Context, cli Runtime) error {
 	ensureBusyboxMu.Lock()
 	defer ensureBusyboxMu.Unlock()
 
@@ -32,7 +32,7 @@ func ensureBusybox(ctx context.Context, cli *client.Client) error {
 	}
 
 	images, err := cli.ImageList(ctx, types.ImageListOptions{
-		Filters: filters.NewArgs(filters.Arg("reference", busyboxRef)),
+		Filters: filters.NewArgs(filters.Arg("reference", BusyboxRef)),
 	})
 	if err != nil {
 		return fmt.Errorf("listing images to check busybox presence: %w", err)
@@ -43,7 +43,7 @@ func ensureBusybox(ctx context.Context, cli *client.Client) error {
 		return nil
 	}
 
-	rc, err := cli.ImagePull(ctx, busyboxRef, types.ImagePullOptions{})
+	rc, err := cli.ImagePull(ctx, BusyboxRef, types.ImagePullOptions{})
 	if err != nil {
 		return err
 	}
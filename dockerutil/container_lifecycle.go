@@ -1,6 +1,7 @@
 package dockerutil
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net"
@@ -9,23 +10,21 @@ import (
 	dockertypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
-	dockerclient "github.com/docker/docker/client"
 	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"go.uber.org/zap"
-
-	"github.com/decentrio/rollup-e2e-testing/ibc"
 )
 
 type ContainerLifecycle struct {
 	log               *zap.Logger
-	client            *dockerclient.Client
+	client            Runtime
 	containerName     string
 	id                string
 	preStartListeners Listeners
 }
 
-func NewContainerLifecycle(log *zap.Logger, client *dockerclient.Client, containerName string) *ContainerLifecycle {
+func NewContainerLifecycle(log *zap.Logger, client Runtime, containerName string) *ContainerLifecycle {
 	return &ContainerLifecycle{
 		log:           log,
 		client:        client,
@@ -33,15 +32,36 @@ func NewContainerLifecycle(log *zap.Logger, client *dockerclient.Client, contain
 	}
 }
 
+// ContainerImage is the image a container is created from, such as an ibc.DockerImage.
+type ContainerImage interface {
+	Ref() string
+}
+
+// ContainerResources limits the CPU and memory of a container. Zero values mean no limit.
+type ContainerResources struct {
+	// Number of CPUs, e.g. 1.5.
+	CPUs float64 `yaml:"cpus"`
+	// Memory in bytes.
+	Memory int64 `yaml:"memory"`
+}
+
+func (r ContainerResources) hostResources() container.Resources {
+	return container.Resources{
+		NanoCPUs: int64(r.CPUs * 1e9),
+		Memory:   r.Memory,
+	}
+}
+
 func (c *ContainerLifecycle) CreateContainer(
 	ctx context.Context,
 	testName string,
 	networkID string,
-	image ibc.DockerImage,
+	image ContainerImage,
 	ports nat.PortSet,
 	volumeBinds []string,
 	hostName string,
 	cmd []string,
+	resources ContainerResources,
 ) error {
 	imageRef := image.Ref()
 	c.log.Info(
@@ -51,7 +71,7 @@ func (c *ContainerLifecycle) CreateContainer(
 		zap.String("command", strings.Join(cmd, " ")),
 	)
 
-	pb, listeners, err := GeneratePortBindings(ports)
+	pb, listeners, err := GeneratePortBindings(ports, DefaultPortAllocation)
 	if err != nil {
 		return fmt.Errorf("failed to generate port bindings: %w", err)
 	}
@@ -79,6 +99,7 @@ func (c *ContainerLifecycle) CreateContainer(
 			AutoRemove:      false,
 			DNS:             []string{},
 			ExtraHosts:      []string{"host.docker.internal:host-gateway"},
+			Resources:       resources.hostResources(),
 		},
 		&network.NetworkingConfig{
 			EndpointsConfig: map[string]*network.EndpointSettings{
@@ -123,6 +144,11 @@ func (c *ContainerLifecycle) UnpauseContainer(ctx context.Context) error {
 	return c.client.ContainerUnpause(ctx, c.id)
 }
 
+// KillContainer stops the container abruptly with SIGKILL, as in a crash.
+func (c *ContainerLifecycle) KillContainer(ctx context.Context) error {
+	return c.client.ContainerKill(ctx, c.id, "SIGKILL")
+}
+
 func (c *ContainerLifecycle) StopContainer(ctx context.Context) error {
 	var timeout container.StopOptions
 	timeoutSec := 30
@@ -142,6 +168,54 @@ func (c *ContainerLifecycle) RemoveContainer(ctx context.Context) error {
 	return nil
 }
 
+// Exec runs cmd in the running container and waits for it to complete.
+// A non-zero exit code returns an error.
+func (c *ContainerLifecycle) Exec(ctx context.Context, cmd []string, env []string) ContainerExecResult {
+	return ExecInContainer(ctx, c.client, c.id, c.containerName, cmd, env)
+}
+
+// ExecInContainer runs cmd in the running container with containerID and waits for it to complete.
+// containerName is only used in errors. A non-zero exit code returns an error.
+func ExecInContainer(ctx context.Context, rt Runtime, containerID, containerName string, cmd []string, env []string) ContainerExecResult {
+	exec, err := rt.ContainerExecCreate(ctx, containerID, dockertypes.ExecConfig{
+		Cmd:          cmd,
+		Env:          env,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return ContainerExecResult{Err: fmt.Errorf("create exec in container %s: %w", containerName, err), ExitCode: -1}
+	}
+
+	resp, err := rt.ContainerExecAttach(ctx, exec.ID, dockertypes.ExecStartCheck{})
+	if err != nil {
+		return ContainerExecResult{Err: fmt.Errorf("attach exec in container %s: %w", containerName, err), ExitCode: -1}
+	}
+	defer resp.Close()
+
+	var stdout, stderr bytes.Buffer
+	// Output is multiplexed into one stream, as for container logs.
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, resp.Reader); err != nil {
+		return ContainerExecResult{Err: fmt.Errorf("read exec output in container %s: %w", containerName, err), ExitCode: -1}
+	}
+
+	inspect, err := rt.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return ContainerExecResult{Err: fmt.Errorf("inspect exec in container %s: %w", containerName, err), ExitCode: -1}
+	}
+
+	res := ContainerExecResult{
+		ExitCode: inspect.ExitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+	}
+	if inspect.ExitCode != 0 {
+		out := strings.Join([]string{stdout.String(), stderr.String()}, " ")
+		res.Err = fmt.Errorf("exit code %d: %s", inspect.ExitCode, out)
+	}
+	return res
+}
+
 func (c *ContainerLifecycle) ContainerID() string {
 	return c.id
 }
@@ -0,0 +1,844 @@
+package dockerutil
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// FakeResult is the outcome of a command run by a FakeRuntime.
+type FakeResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+
+	// Running keeps a started container running until it is stopped, as a daemon would.
+	// It is ignored for exec commands.
+	Running bool
+}
+
+// FakeRunFunc returns the outcome of cmd, the entrypoint and command of a started container
+// or the command of an exec, run in the container named containerName.
+type FakeRunFunc func(containerName string, cmd []string) FakeResult
+
+// FakeRuntime is an in-memory Runtime to test code running containers without a Docker daemon.
+// Nothing is executed: the outcome of commands comes from RunFunc.
+// Files copied to containers are kept in memory and shared by the containers binding the same host path.
+type FakeRuntime struct {
+	// RunFunc is called when a container is started or a command is executed in a container.
+	// If nil, commands exit with code 0 and no output.
+	RunFunc FakeRunFunc
+
+	// HostPortFunc, if set, returns the host port to publish port of the container named containerName on,
+	// e.g. to route it to a server of the test. An empty port falls back to the bound or assigned one.
+	HostPortFunc func(containerName string, port nat.Port) string
+
+	mu         sync.Mutex
+	seq        int
+	port       int
+	containers map[string]*fakeContainer
+	removed    map[string]*fakeContainer
+	execs      map[string]*fakeExec
+	files      map[string]map[string][]byte
+	images     map[string]map[string]string
+	volumes    map[string]volume.Volume
+	networks   map[string]types.NetworkCreate
+}
+
+var _ Runtime = (*FakeRuntime)(nil)
+
+type fakeContainer struct {
+	id, name   string
+	config     container.Config
+	hostConfig container.HostConfig
+	networks   map[string]*network.EndpointSettings
+
+	// ports are the host ports published while the container runs.
+	ports nat.PortMap
+
+	running, paused bool
+	exitCode        int
+	startedAt       time.Time
+	finishedAt      time.Time
+	stdout, stderr  bytes.Buffer
+
+	// done is closed when the container exits.
+	done chan struct{}
+}
+
+type fakeExec struct {
+	containerID string
+	cmd         []string
+	exitCode    int
+}
+
+// NewFakeRuntime returns an empty FakeRuntime.
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		containers: make(map[string]*fakeContainer),
+		removed:    make(map[string]*fakeContainer),
+		execs:      make(map[string]*fakeExec),
+		files:      make(map[string]map[string][]byte),
+		images:     make(map[string]map[string]string),
+		volumes:    make(map[string]volume.Volume),
+		networks:   make(map[string]types.NetworkCreate),
+	}
+}
+
+func (f *FakeRuntime) nextID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s%06d", prefix, f.seq)
+}
+
+// publishPorts returns the host ports of c when started: the bound ports, or new ones as Docker
+// assigns when the host port is empty or the exposed port is not bound and all ports are published.
+// f.mu must be held.
+func (f *FakeRuntime) publishPorts(c *fakeContainer) nat.PortMap {
+	ports := make(nat.PortMap)
+	for p := range c.config.ExposedPorts {
+		bindings, ok := c.hostConfig.PortBindings[p]
+		if !ok && !c.hostConfig.PublishAllPorts {
+			continue
+		}
+		if len(bindings) == 0 {
+			bindings = []nat.PortBinding{{}}
+		}
+		for _, b := range bindings {
+			if b.HostIP == "" {
+				b.HostIP = "0.0.0.0"
+			}
+			if f.HostPortFunc != nil {
+				if hostPort := f.HostPortFunc(c.name, p); hostPort != "" {
+					b.HostPort = hostPort
+				}
+			}
+			if b.HostPort == "" {
+				f.port++
+				b.HostPort = fmt.Sprint(32768 + f.port)
+			}
+			ports[p] = append(ports[p], b)
+		}
+	}
+	return ports
+}
+
+func (f *FakeRuntime) run(containerName string, cmd []string) FakeResult {
+	if f.RunFunc == nil {
+		return FakeResult{}
+	}
+	return f.RunFunc(containerName, cmd)
+}
+
+// container returns the container with the given ID or name. f.mu must be held.
+func (f *FakeRuntime) container(idOrName string) (*fakeContainer, error) {
+	if c, ok := f.containers[idOrName]; ok {
+		return c, nil
+	}
+	name := strings.TrimPrefix(idOrName, "/")
+	for _, c := range f.containers {
+		if c.name == name {
+			return c, nil
+		}
+	}
+	return nil, errdefs.NotFound(fmt.Errorf("No such container: %s", idOrName))
+}
+
+// exit stops a running container with exitCode. f.mu must be held.
+func (f *FakeRuntime) exit(c *fakeContainer, exitCode int) {
+	c.running, c.paused = false, false
+	c.ports = nil
+	c.exitCode = exitCode
+	c.finishedAt = time.Now()
+	close(c.done)
+	if c.hostConfig.AutoRemove {
+		f.remove(c)
+	}
+}
+
+// remove deletes a container and its files. f.mu must be held.
+func (f *FakeRuntime) remove(c *fakeContainer) {
+	delete(f.containers, c.id)
+	f.removed[c.id] = c
+	delete(f.files, "container:"+c.id)
+}
+
+func (f *FakeRuntime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if containerName != "" {
+		if _, err := f.container(containerName); err == nil {
+			return container.CreateResponse{}, errdefs.Conflict(fmt.Errorf("container name %q is already in use", containerName))
+		}
+	}
+
+	c := &fakeContainer{id: f.nextID("fake"), name: containerName}
+	if c.name == "" {
+		c.name = c.id
+	}
+	if config != nil {
+		c.config = *config
+	}
+	if hostConfig != nil {
+		c.hostConfig = *hostConfig
+	}
+	c.networks = make(map[string]*network.EndpointSettings)
+	if networkingConfig != nil {
+		for networkID := range networkingConfig.EndpointsConfig {
+			c.networks[networkID] = f.endpoint(networkID)
+		}
+	}
+	f.containers[c.id] = c
+	return container.CreateResponse{ID: c.id}, nil
+}
+
+func (f *FakeRuntime) ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error {
+	f.mu.Lock()
+	c, err := f.container(containerID)
+	if err != nil || c.running {
+		f.mu.Unlock()
+		return err
+	}
+	cmd := append(append([]string{}, c.config.Entrypoint...), c.config.Cmd...)
+	name := c.name
+	f.mu.Unlock()
+
+	res := f.run(name, cmd)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c.running = true
+	c.startedAt = time.Now()
+	c.ports = f.publishPorts(c)
+	c.done = make(chan struct{})
+	c.stdout.Write(res.Stdout)
+	c.stderr.Write(res.Stderr)
+	if !res.Running {
+		f.exit(c, res.ExitCode)
+	}
+	return nil
+}
+
+func (f *FakeRuntime) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if c.running {
+		f.exit(c, 0)
+	}
+	return nil
+}
+
+func (f *FakeRuntime) ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if c.running {
+		if !options.Force {
+			return errdefs.Conflict(fmt.Errorf("cannot remove running container %s", containerID))
+		}
+		f.exit(c, 137)
+	}
+	f.remove(c)
+	return nil
+}
+
+func (f *FakeRuntime) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	resC := make(chan container.WaitResponse, 1)
+	errC := make(chan error, 1)
+
+	f.mu.Lock()
+	c, err := f.container(containerID)
+	if removed, ok := f.removed[containerID]; ok {
+		// Waiting on an auto-removed container returns its exit code.
+		c, err = removed, nil
+	}
+	f.mu.Unlock()
+	if err != nil {
+		errC <- err
+		return resC, errC
+	}
+
+	go func() {
+		f.mu.Lock()
+		done := c.done
+		f.mu.Unlock()
+		if done != nil {
+			select {
+			case <-ctx.Done():
+				errC <- ctx.Err()
+				return
+			case <-done:
+			}
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		resC <- container.WaitResponse{StatusCode: int64(c.exitCode)}
+	}()
+	return resC, errC
+}
+
+func (f *FakeRuntime) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+
+	status := "created"
+	switch {
+	case c.paused:
+		status = "paused"
+	case c.running:
+		status = "running"
+	case !c.finishedAt.IsZero():
+		status = "exited"
+	}
+
+	config := c.config
+	hostConfig := c.hostConfig
+	networks := make(map[string]*network.EndpointSettings, len(c.networks))
+	for networkID, e := range c.networks {
+		endpoint := *e
+		networks[networkID] = &endpoint
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    c.id,
+			Name:  "/" + c.name,
+			Image: c.config.Image,
+			State: &types.ContainerState{
+				Status:     status,
+				Running:    c.running,
+				Paused:     c.paused,
+				ExitCode:   c.exitCode,
+				StartedAt:  formatFakeTime(c.startedAt),
+				FinishedAt: formatFakeTime(c.finishedAt),
+			},
+			HostConfig: &hostConfig,
+		},
+		Config: &config,
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{Ports: c.ports},
+			Networks:            networks,
+		},
+	}, nil
+}
+
+func formatFakeTime(t time.Time) string {
+	if t.IsZero() {
+		return "0001-01-01T00:00:00Z"
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func (f *FakeRuntime) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var list []types.Container
+	for _, c := range f.containers {
+		if !options.All && !c.running {
+			continue
+		}
+		if !options.Filters.MatchKVList("label", c.config.Labels) {
+			continue
+		}
+		if options.Filters.Contains("name") && !options.Filters.Match("name", c.name) {
+			continue
+		}
+		state := "created"
+		if c.running {
+			state = "running"
+		} else if !c.finishedAt.IsZero() {
+			state = "exited"
+		}
+		list = append(list, types.Container{
+			ID:     c.id,
+			Names:  []string{"/" + c.name},
+			Image:  c.config.Image,
+			Labels: c.config.Labels,
+			State:  state,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// ContainerLogs returns the multiplexed output of the container, see stdcopy.
+// With options.Follow, the logs end when the container exits.
+func (f *FakeRuntime) ContainerLogs(ctx context.Context, containerID string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	c, err := f.container(containerID)
+	if err != nil {
+		f.mu.Unlock()
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if options.ShowStdout {
+		_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write(c.stdout.Bytes())
+	}
+	if options.ShowStderr {
+		_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write(c.stderr.Bytes())
+	}
+	done := c.done
+	follow := options.Follow && c.running
+	f.mu.Unlock()
+
+	if !follow {
+		return io.NopCloser(&buf), nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		if _, err := pw.Write(buf.Bytes()); err != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			_ = pw.CloseWithError(ctx.Err())
+		case <-done:
+			_ = pw.Close()
+		}
+	}()
+	return pr, nil
+}
+
+func (f *FakeRuntime) ContainerPause(ctx context.Context, containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if !c.running {
+		return errdefs.Conflict(fmt.Errorf("container %s is not running", containerID))
+	}
+	c.paused = true
+	return nil
+}
+
+func (f *FakeRuntime) ContainerUnpause(ctx context.Context, containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if !c.paused {
+		return errdefs.Conflict(fmt.Errorf("container %s is not paused", containerID))
+	}
+	c.paused = false
+	return nil
+}
+
+// ContainerKill stops a running container with the exit code of a SIGKILL, whatever the signal.
+func (f *FakeRuntime) ContainerKill(ctx context.Context, containerID, signal string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if !c.running {
+		return errdefs.Conflict(fmt.Errorf("container %s is not running", containerID))
+	}
+	f.exit(c, 137)
+	return nil
+}
+
+// ContainerStats reports no usage, and the memory limit of the container if any.
+func (f *FakeRuntime) ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return types.ContainerStats{}, err
+	}
+
+	var stats types.StatsJSON
+	stats.Name = "/" + c.name
+	stats.ID = c.id
+	stats.Read = time.Now()
+	stats.MemoryStats.Limit = uint64(c.hostConfig.Memory)
+	b, err := json.Marshal(stats)
+	if err != nil {
+		return types.ContainerStats{}, err
+	}
+	return types.ContainerStats{Body: io.NopCloser(bytes.NewReader(b)), OSType: "linux"}, nil
+}
+
+func (f *FakeRuntime) ContainerExecCreate(ctx context.Context, containerID string, config types.ExecConfig) (types.IDResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return types.IDResponse{}, err
+	}
+	if !c.running || c.paused {
+		return types.IDResponse{}, errdefs.Conflict(fmt.Errorf("container %s is not running", containerID))
+	}
+	id := f.nextID("exec")
+	f.execs[id] = &fakeExec{containerID: c.id, cmd: config.Cmd}
+	return types.IDResponse{ID: id}, nil
+}
+
+// ContainerExecAttach runs the exec and returns its multiplexed output, see stdcopy.
+func (f *FakeRuntime) ContainerExecAttach(ctx context.Context, execID string, config types.ExecStartCheck) (types.HijackedResponse, error) {
+	f.mu.Lock()
+	e, ok := f.execs[execID]
+	var name string
+	if ok {
+		if c, err := f.container(e.containerID); err == nil {
+			name = c.name
+		}
+	}
+	f.mu.Unlock()
+	if !ok {
+		return types.HijackedResponse{}, errdefs.NotFound(fmt.Errorf("No such exec instance: %s", execID))
+	}
+
+	res := f.run(name, e.cmd)
+
+	f.mu.Lock()
+	e.exitCode = res.ExitCode
+	f.mu.Unlock()
+
+	var buf bytes.Buffer
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write(res.Stdout)
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write(res.Stderr)
+
+	client, server := net.Pipe()
+	go func() {
+		_, _ = server.Write(buf.Bytes())
+		_ = server.Close()
+	}()
+	return types.HijackedResponse{Conn: client, Reader: bufio.NewReader(client)}, nil
+}
+
+func (f *FakeRuntime) ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.execs[execID]
+	if !ok {
+		return types.ContainerExecInspect{}, errdefs.NotFound(fmt.Errorf("No such exec instance: %s", execID))
+	}
+	return types.ContainerExecInspect{ExecID: execID, ContainerID: e.containerID, ExitCode: e.exitCode}, nil
+}
+
+// fileStore returns the key of the files visible at p in container c and the path of p in them.
+// Paths under a named volume are stored under the volume, and paths under a host path are stored
+// under their host path, so binds of nested host paths share their files as on the host.
+func fileStore(c *fakeContainer, p string) (string, string) {
+	p = path.Clean("/" + p)
+	for _, bind := range c.hostConfig.Binds {
+		parts := strings.SplitN(bind, ":", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		src, dst := parts[0], path.Clean(parts[1])
+		var rel string
+		switch {
+		case p == dst:
+			rel = "/"
+		case strings.HasPrefix(p, dst+"/"):
+			rel = strings.TrimPrefix(p, dst)
+		default:
+			continue
+		}
+		if strings.HasPrefix(src, "/") {
+			return "host", path.Join(src, rel)
+		}
+		return src, rel
+	}
+	return "container:" + c.id, p
+}
+
+func (f *FakeRuntime) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options types.CopyToContainerOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(content)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading tar: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("reading tar: %w", err)
+		}
+
+		key, p := fileStore(c, path.Join(dstPath, hdr.Name))
+		if f.files[key] == nil {
+			f.files[key] = make(map[string][]byte)
+		}
+		f.files[key][p] = data
+	}
+}
+
+// CopyFromContainer returns a tar archive of the file or directory at srcPath,
+// its entries being relative to the parent directory of srcPath as with Docker.
+func (f *FakeRuntime) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return nil, types.ContainerPathStat{}, err
+	}
+
+	key, p := fileStore(c, srcPath)
+	files := f.files[key]
+
+	var names []string
+	for name := range files {
+		if name == p || p == "/" || strings.HasPrefix(name, p+"/") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, types.ContainerPathStat{}, errdefs.NotFound(fmt.Errorf("Could not find the file %s in container %s", srcPath, containerID))
+	}
+	sort.Strings(names)
+
+	var (
+		buf  bytes.Buffer
+		tw   = tar.NewWriter(&buf)
+		base = path.Dir(p)
+		stat = types.ContainerPathStat{Name: path.Base(srcPath)}
+	)
+	for _, name := range names {
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, base), "/")
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Size: int64(len(files[name])), Mode: 0644, Format: tar.FormatPAX}); err != nil {
+			return nil, types.ContainerPathStat{}, err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return nil, types.ContainerPathStat{}, err
+		}
+		stat.Size += int64(len(files[name]))
+	}
+	if err := tw.Close(); err != nil {
+		return nil, types.ContainerPathStat{}, err
+	}
+	return io.NopCloser(&buf), stat, nil
+}
+
+// ImagePull marks the image as present.
+func (f *FakeRuntime) ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.images[refStr]; !ok {
+		f.images[refStr] = nil
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// ImageBuild reads the build context and tags an image with the build labels, without running the Dockerfile.
+// A context without the Dockerfile fails the build, as with Docker.
+func (f *FakeRuntime) ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+	dockerfile := options.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	found := false
+	tr := tar.NewReader(buildContext)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return types.ImageBuildResponse{}, err
+		}
+		if path.Clean(hdr.Name) == path.Clean(dockerfile) {
+			found = true
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	if !found {
+		_ = enc.Encode(map[string]any{"errorDetail": map[string]string{"message": "Cannot locate specified Dockerfile: " + dockerfile}})
+	} else {
+		for _, tag := range options.Tags {
+			f.images[tag] = options.Labels
+		}
+		_ = enc.Encode(map[string]string{"stream": "Successfully built\n"})
+	}
+	return types.ImageBuildResponse{Body: io.NopCloser(&out), OSType: "linux"}, nil
+}
+
+func (f *FakeRuntime) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var list []types.ImageSummary
+	for ref := range f.images {
+		if options.Filters.Contains("reference") && !options.Filters.ExactMatch("reference", ref) {
+			continue
+		}
+		list = append(list, types.ImageSummary{ID: ref, RepoTags: []string{ref}})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (f *FakeRuntime) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	labels, ok := f.images[imageID]
+	if !ok {
+		return types.ImageInspect{}, nil, errdefs.NotFound(fmt.Errorf("No such image: %s", imageID))
+	}
+	return types.ImageInspect{ID: imageID, RepoTags: []string{imageID}, Config: &container.Config{Labels: labels}}, nil, nil
+}
+
+func (f *FakeRuntime) VolumeCreate(ctx context.Context, options volume.CreateOptions) (volume.Volume, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := options.Name
+	if name == "" {
+		name = f.nextID("volume")
+	}
+	v := volume.Volume{Name: name, Driver: "local", Labels: options.Labels, Mountpoint: "/var/lib/docker/volumes/" + name + "/_data"}
+	f.volumes[name] = v
+	return v, nil
+}
+
+func (f *FakeRuntime) VolumesPrune(ctx context.Context, pruneFilters filters.Args) (types.VolumesPruneReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var report types.VolumesPruneReport
+	for name, v := range f.volumes {
+		if pruneFilters.MatchKVList("label", v.Labels) {
+			delete(f.volumes, name)
+			report.VolumesDeleted = append(report.VolumesDeleted, name)
+		}
+	}
+	sort.Strings(report.VolumesDeleted)
+	return report, nil
+}
+
+func (f *FakeRuntime) NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if name == "" {
+		return types.NetworkCreateResponse{}, errors.New("network name cannot be empty")
+	}
+	id := f.nextID("network")
+	f.networks[id] = options
+	return types.NetworkCreateResponse{ID: id}, nil
+}
+
+func (f *FakeRuntime) NetworksPrune(ctx context.Context, pruneFilters filters.Args) (types.NetworksPruneReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var report types.NetworksPruneReport
+	for id, n := range f.networks {
+		if pruneFilters.MatchKVList("label", n.Labels) {
+			delete(f.networks, id)
+			report.NetworksDeleted = append(report.NetworksDeleted, id)
+		}
+	}
+	sort.Strings(report.NetworksDeleted)
+	return report, nil
+}
+
+// endpoint returns the settings of a new endpoint on networkID, with a unique IP address. f.mu must be held.
+func (f *FakeRuntime) endpoint(networkID string) *network.EndpointSettings {
+	f.seq++
+	return &network.EndpointSettings{
+		NetworkID: networkID,
+		IPAddress: fmt.Sprintf("10.%d.%d.%d", (f.seq>>16)&0xff, (f.seq>>8)&0xff, f.seq&0xff),
+	}
+}
+
+func (f *FakeRuntime) NetworkConnect(ctx context.Context, networkID, containerID string, config *network.EndpointSettings) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.networks[networkID]; ok {
+		return errdefs.Forbidden(fmt.Errorf("container %s is already connected to network %s", containerID, networkID))
+	}
+	c.networks[networkID] = f.endpoint(networkID)
+	return nil
+}
+
+func (f *FakeRuntime) NetworkDisconnect(ctx context.Context, networkID, containerID string, force bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.networks[networkID]; !ok {
+		return errdefs.Forbidden(fmt.Errorf("container %s is not connected to network %s", containerID, networkID))
+	}
+	delete(c.networks, networkID)
+	return nil
+}
@@ -0,0 +1,85 @@
+package dockerutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestFakeRuntimeFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rt := NewFakeRuntime()
+	log := zaptest.NewLogger(t)
+
+	err := NewFileWriter(log, rt, t.Name()).WriteFile(ctx, "val0", "chain", "config/app.toml", []byte("minimum-gas-prices"))
+	require.NoError(t, err)
+
+	content, err := NewFileRetriever(log, rt, t.Name()).SingleFileContent(ctx, "val0", "chain", "config/app.toml")
+	require.NoError(t, err)
+	require.Equal(t, "minimum-gas-prices", string(content))
+
+	_, err = NewFileRetriever(log, rt, t.Name()).SingleFileContent(ctx, "val1", "chain", "config/app.toml")
+	require.Error(t, err)
+}
+
+func TestFakeRuntimeRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rt := NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) FakeResult {
+		if cmd[0] == "fail" {
+			return FakeResult{Stderr: []byte("boom"), ExitCode: 2}
+		}
+		return FakeResult{Stdout: []byte(strings.Join(cmd, " "))}
+	}
+	image := NewImage(zaptest.NewLogger(t), rt, "network", t.Name(), "busybox", "stable")
+
+	res := image.Run(ctx, []string{"echo", "hello"}, ContainerOptions{})
+	require.NoError(t, res.Err)
+	require.Equal(t, "echo hello", string(res.Stdout))
+
+	res = image.Run(ctx, []string{"fail"}, ContainerOptions{})
+	require.Error(t, res.Err)
+	require.Equal(t, 2, res.ExitCode)
+
+	containers, err := rt.ContainerList(ctx, types.ContainerListOptions{All: true})
+	require.NoError(t, err)
+	require.Empty(t, containers)
+}
+
+func TestFakeRuntimeContainerLifecycle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rt := NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) FakeResult {
+		if strings.Join(cmd, " ") == "simd start" {
+			return FakeResult{Running: true}
+		}
+		return FakeResult{Stdout: []byte(containerName + ": " + strings.Join(cmd, " "))}
+	}
+
+	c := NewContainerLifecycle(zaptest.NewLogger(t), rt, "node")
+	require.NoError(t, c.CreateContainer(ctx, t.Name(), "network", fakeImage{}, nil, nil, "node", []string{"simd", "start"}, ContainerResources{}))
+	require.NoError(t, c.StartContainer(ctx))
+	require.NoError(t, c.Running(ctx))
+
+	res := c.Exec(ctx, []string{"simd", "status"}, nil)
+	require.NoError(t, res.Err)
+	require.Equal(t, "node: simd status", string(res.Stdout))
+
+	require.NoError(t, c.StopContainer(ctx))
+	require.Error(t, c.Running(ctx))
+	require.NoError(t, c.RemoveContainer(ctx))
+}
+
+type fakeImage struct{}
+
+func (fakeImage) Ref() string { return "simd:latest" }
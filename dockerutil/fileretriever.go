@@ -10,7 +10,6 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"go.uber.org/zap"
 )
 
@@ -19,13 +18,13 @@ import (
 type FileRetriever struct {
 	log *zap.Logger
 
-	cli *client.Client
+	cli Runtime
 
 	testName string
 }
 
 // NewFileRetriever returns a new FileRetriever.
-func NewFileRetriever(log *zap.Logger, cli *client.Client, testName string) *FileRetriever {
+func NewFileRetriever(log *zap.Logger, cli Runtime, testName string) *FileRetriever {
 	return &FileRetriever{log: log, cli: cli, testName: testName}
 }
 
@@ -42,7 +41,7 @@ func (r *FileRetriever) SingleFileContent(ctx context.Context, volumeName, chain
 	cc, err := r.cli.ContainerCreate(
 		ctx,
 		&container.Config{
-			Image: busyboxRef,
+			Image: BusyboxRef,
 
 			// Use root user to avoid permission issues when reading files from the volume.
 			User: GetRootUserString(),
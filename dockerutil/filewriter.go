@@ -9,7 +9,6 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"go.uber.org/zap"
 )
 
@@ -18,13 +17,13 @@ import (
 type FileWriter struct {
 	log *zap.Logger
 
-	cli *client.Client
+	cli Runtime
 
 	testName string
 }
 
 // NewFileWriter returns a new FileWriter.
-func NewFileWriter(log *zap.Logger, cli *client.Client, testName string) *FileWriter {
+func NewFileWriter(log *zap.Logger, cli Runtime, testName string) *FileWriter {
 	return &FileWriter{log: log, cli: cli, testName: testName}
 }
 
@@ -41,7 +40,7 @@ func (w *FileWriter) WriteFile(ctx context.Context, volumeName, chainName, relPa
 	cc, err := w.cli.ContainerCreate(
 		ctx,
 		&container.Config{
-			Image: busyboxRef,
+			Image: BusyboxRef,
 
 			Entrypoint: []string{"sh", "-c"},
 			Cmd: []string{
@@ -154,7 +153,7 @@ func (w *FileWriter) RelayerWriteFile(ctx context.Context, volumeName, relayerNa
 	cc, err := w.cli.ContainerCreate(
 		ctx,
 		&container.Config{
-			Image: busyboxRef,
+			Image: BusyboxRef,
 
 			Entrypoint: []string{"sh", "-c"},
 			Cmd: []string{
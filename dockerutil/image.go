@@ -14,7 +14,6 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
-	"github.com/docker/docker/client"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/stdcopy"
 	"go.uber.org/zap"
@@ -23,7 +22,7 @@ import (
 // Image is a docker image.
 type Image struct {
 	log    *zap.Logger
-	client *client.Client
+	client Runtime
 
 	// NOTE: it might make sense for Image to have an ibc.DockerImage field,
 	// but for now it is probably better to not have dockerutil depend on ibc.
@@ -41,7 +40,7 @@ type Image struct {
 // Most arguments (except tag) must be non-zero values or this function panics.
 // If tag is absent, defaults to "latest".
 // Currently, only public docker images are supported.
-func NewImage(logger *zap.Logger, cli *client.Client, networkID string, testName string, repository, tag string) *Image {
+func NewImage(logger *zap.Logger, cli Runtime, networkID string, testName string, repository, tag string) *Image {
 	if logger == nil {
 		panic(errors.New("nil logger"))
 	}
@@ -0,0 +1,134 @@
+package dockerutil
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Backend is the container engine behind a Runtime.
+type Backend string
+
+const (
+	BackendDocker Backend = "docker"
+	BackendPodman Backend = "podman"
+)
+
+// DefaultBackend is the backend DockerSetup connects to when no WithBackend option is given.
+//
+// The value is BackendDocker by default, but can be initialized by setting the
+// environment variable IBCTEST_CONTAINER_BACKEND, e.g. to "podman" on rootless CI runners.
+var DefaultBackend = backendFromEnv()
+
+func backendFromEnv() Backend {
+	if b := Backend(os.Getenv("IBCTEST_CONTAINER_BACKEND")); b != "" {
+		return b
+	}
+	return BackendDocker
+}
+
+// BackendOf returns the backend of rt. Runtimes that do not report a backend are Docker compatible.
+func BackendOf(rt Runtime) Backend {
+	if b, ok := rt.(interface{ Backend() Backend }); ok {
+		return b.Backend()
+	}
+	return BackendDocker
+}
+
+// podmanHost returns the address of the Docker compatible API socket of Podman,
+// the rootless socket of the current user unless CONTAINER_HOST or DOCKER_HOST is set.
+func podmanHost() string {
+	for _, env := range []string{"CONTAINER_HOST", "DOCKER_HOST"} {
+		if host := os.Getenv(env); host != "" {
+			return host
+		}
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return "unix://" + dir + "/podman/podman.sock"
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// PodmanRuntime is a Runtime talking to the Docker compatible API of Podman, rootless or not.
+//
+// It adapts what differs from Docker:
+//   - Podman resolves host.docker.internal by itself and may not support the host-gateway address,
+//     so host-gateway extra hosts are dropped.
+//   - Named volumes are chowned to the container user by Podman (the U option), as Docker does
+//     for new volumes. Host path binds are left as is, Podman would chown the host directory recursively.
+//   - Short image names are qualified with docker.io, as Podman may refuse to resolve them.
+type PodmanRuntime struct {
+	*client.Client
+}
+
+// NewPodmanRuntime returns a PodmanRuntime connected to host, or to the default Podman socket if host is empty.
+func NewPodmanRuntime(host string) (*PodmanRuntime, error) {
+	if host == "" {
+		host = podmanHost()
+	}
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithHost(host), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+	return &PodmanRuntime{Client: cli}, nil
+}
+
+// Backend implements the interface used by BackendOf.
+func (r *PodmanRuntime) Backend() Backend {
+	return BackendPodman
+}
+
+func (r *PodmanRuntime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	if hostConfig != nil {
+		hc := *hostConfig
+
+		hc.ExtraHosts = nil
+		for _, h := range hostConfig.ExtraHosts {
+			if !strings.HasSuffix(h, ":host-gateway") {
+				hc.ExtraHosts = append(hc.ExtraHosts, h)
+			}
+		}
+
+		hc.Binds = make([]string, len(hostConfig.Binds))
+		for i, bind := range hostConfig.Binds {
+			hc.Binds[i] = chownBind(bind)
+		}
+
+		hostConfig = &hc
+	}
+	return r.Client.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+func (r *PodmanRuntime) ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	if named, err := reference.ParseNormalizedNamed(refStr); err == nil {
+		refStr = named.String()
+	}
+	return r.Client.ImagePull(ctx, refStr, options)
+}
+
+// chownBind adds the U option to a named volume mount, so Podman chowns it to the container user.
+func chownBind(bind string) string {
+	parts := strings.SplitN(bind, ":", 3)
+	switch {
+	case len(parts) < 2 || strings.HasPrefix(parts[0], "/") || strings.HasPrefix(parts[0], "."):
+		// Host paths are owned by the host user, see SetVolumeOwner.
+		return bind
+	case len(parts) == 2:
+		return bind + ":U"
+	default:
+		for _, opt := range strings.Split(parts[2], ",") {
+			if opt == "U" {
+				return bind
+			}
+		}
+		return bind + ",U"
+	}
+}
@@ -0,0 +1,23 @@
+package dockerutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChownBind(t *testing.T) {
+	t.Parallel()
+
+	for bind, want := range map[string]string{
+		"data:/var/cosmos-chain":      "data:/var/cosmos-chain:U",
+		"data:/var/cosmos-chain:ro":   "data:/var/cosmos-chain:ro,U",
+		"data:/var/cosmos-chain:ro,U": "data:/var/cosmos-chain:ro,U",
+		"/tmp:/var/cosmos-chain":      "/tmp:/var/cosmos-chain",
+		"/tmp/rly:/home/relayer:ro":   "/tmp/rly:/home/relayer:ro",
+		"./testdata:/home/relayer":    "./testdata:/home/relayer",
+		"/var/cosmos-chain":           "/var/cosmos-chain",
+	} {
+		require.Equal(t, want, chownBind(bind), bind)
+	}
+}
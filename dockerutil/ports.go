@@ -3,6 +3,7 @@ package dockerutil
 import (
 	"fmt"
 	"net"
+	"os"
 	"sync"
 
 	"github.com/docker/go-connections/nat"
@@ -10,6 +11,36 @@ import (
 
 var mu sync.RWMutex
 
+// PortAllocation selects how the exposed ports of a container are bound to host ports.
+type PortAllocation string
+
+const (
+	// PortAllocationDocker lets the container engine assign free host ports when the container starts,
+	// so no other test or process can take them in between.
+	// The host ports change when the container restarts, so they must be read with GetHostPorts after every start.
+	PortAllocationDocker PortAllocation = "docker"
+
+	// PortAllocationListener reserves free host ports with listeners from the creation of the container
+	// until it starts. The host ports are kept across restarts, but another process may take a port
+	// between the listeners closing and the container starting.
+	PortAllocationListener PortAllocation = "listener"
+)
+
+// DefaultPortAllocation is the port allocation of the containers created by ContainerLifecycle.
+//
+// The value is PortAllocationDocker by default. The configs of the relayers running on the host
+// are rewritten when a node starts on new host ports, see ibc.HostAddressNotifier.
+// It can be initialized by setting the environment variable IBCTEST_PORT_ALLOCATION, e.g. to "listener"
+// to keep the host ports of the nodes across restarts.
+var DefaultPortAllocation = portAllocationFromEnv()
+
+func portAllocationFromEnv() PortAllocation {
+	if a := PortAllocation(os.Getenv("IBCTEST_PORT_ALLOCATION")); a != "" {
+		return a
+	}
+	return PortAllocationDocker
+}
+
 type Listeners []net.Listener
 
 func (l Listeners) CloseAll() {
@@ -42,7 +73,6 @@ func openListenerOnFreePort() (*net.TCPListener, error) {
 func nextAvailablePort() (nat.PortBinding, *net.TCPListener, error) {
 	l, err := openListenerOnFreePort()
 	if err != nil {
-		l.Close()
 		return nat.PortBinding{}, nil, err
 	}
 
@@ -52,10 +82,25 @@ func nextAvailablePort() (nat.PortBinding, *net.TCPListener, error) {
 	}, l, nil
 }
 
-// GeneratePortBindings will find open ports on the local
-// machine and create a PortBinding for every port in the portSet.
-func GeneratePortBindings(portSet nat.PortSet) (nat.PortMap, Listeners, error) {
+// GeneratePortBindings creates a PortBinding for every port in the portSet according to allocation.
+//
+// With PortAllocationListener, it finds open ports on the local machine and keeps them open with
+// the returned listeners, to be closed just before the container starts.
+// With PortAllocationDocker, the host ports are left empty for the engine to assign, and no listener is returned.
+func GeneratePortBindings(portSet nat.PortSet, allocation PortAllocation) (nat.PortMap, Listeners, error) {
 	m := make(nat.PortMap)
+
+	switch allocation {
+	case PortAllocationDocker:
+		for p := range portSet {
+			m[p] = []nat.PortBinding{{HostIP: "0.0.0.0"}}
+		}
+		return m, nil, nil
+	case PortAllocationListener:
+	default:
+		return nil, nil, fmt.Errorf("unknown port allocation %q", allocation)
+	}
+
 	listeners := make(Listeners, 0, len(portSet))
 
 	for p := range portSet {
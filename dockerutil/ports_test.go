@@ -0,0 +1,94 @@
+package dockerutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGeneratePortBindingsDocker(t *testing.T) {
+	t.Parallel()
+
+	pb, listeners, err := GeneratePortBindings(nat.PortSet{"26657/tcp": {}}, PortAllocationDocker)
+	require.NoError(t, err)
+	require.Empty(t, listeners)
+	require.Equal(t, nat.PortMap{"26657/tcp": {{HostIP: "0.0.0.0"}}}, pb)
+
+	_, _, err = GeneratePortBindings(nat.PortSet{"26657/tcp": {}}, "random")
+	require.Error(t, err)
+}
+
+func TestDefaultPortAllocation(t *testing.T) {
+	t.Setenv("IBCTEST_PORT_ALLOCATION", "")
+	require.Equal(t, PortAllocationDocker, portAllocationFromEnv())
+
+	t.Setenv("IBCTEST_PORT_ALLOCATION", "listener")
+	require.Equal(t, PortAllocationListener, portAllocationFromEnv())
+}
+
+// restartHostPorts returns the host port of a container before and after a restart.
+func restartHostPorts(t *testing.T) (before, after string) {
+	ctx := context.Background()
+	rt := NewFakeRuntime()
+	rt.RunFunc = func(string, []string) FakeResult {
+		return FakeResult{Running: true}
+	}
+
+	c := NewContainerLifecycle(zaptest.NewLogger(t), rt, "node")
+	require.NoError(t, c.CreateContainer(ctx, t.Name(), "network", fakeImage{}, nat.PortSet{"26657/tcp": {}}, nil, "node", []string{"simd", "start"}, ContainerResources{}))
+
+	require.NoError(t, c.StartContainer(ctx))
+	ports, err := c.GetHostPorts(ctx, "26657/tcp")
+	require.NoError(t, err)
+	before = ports[0]
+
+	require.NoError(t, c.StopContainer(ctx))
+	require.NoError(t, c.StartContainer(ctx))
+	ports, err = c.GetHostPorts(ctx, "26657/tcp")
+	require.NoError(t, err)
+	after = ports[0]
+
+	require.NotEmpty(t, before)
+	require.NotEmpty(t, after)
+	return before, after
+}
+
+func TestHostPortsKeptOnRestart(t *testing.T) {
+	// Not parallel: DefaultPortAllocation is read by the containers of the parallel tests.
+	allocation := DefaultPortAllocation
+	DefaultPortAllocation = PortAllocationListener
+	t.Cleanup(func() { DefaultPortAllocation = allocation })
+
+	before, after := restartHostPorts(t)
+	require.Equal(t, before, after)
+}
+
+// TestHostPortsRefreshedOnRestart checks that the default allocation lets the engine assign new host ports
+// on restart, which are read back with GetHostPorts.
+func TestHostPortsRefreshedOnRestart(t *testing.T) {
+	t.Parallel()
+
+	before, after := restartHostPorts(t)
+	require.NotEqual(t, before, after)
+}
+
+func TestGetHostPortPrefersIPv4(t *testing.T) {
+	t.Parallel()
+
+	cont := types.ContainerJSON{
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{
+				Ports: nat.PortMap{"26657/tcp": {
+					{HostIP: "::", HostPort: "32769"},
+					{HostIP: "0.0.0.0", HostPort: "32768"},
+				}},
+			},
+		},
+	}
+	require.Equal(t, "127.0.0.1:32768", GetHostPort(cont, "26657/tcp"))
+	require.Empty(t, GetHostPort(cont, "1317/tcp"))
+}
@@ -0,0 +1,90 @@
+package dockerutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"go.uber.org/zap"
+)
+
+// PullPolicy determines when an image is pulled.
+type PullPolicy string
+
+const (
+	// PullAlways pulls the image every time, but tolerates a failed pull of an image present locally.
+	// It is the default policy.
+	PullAlways PullPolicy = "Always"
+	// PullIfNotPresent pulls the image only if it is not present locally.
+	PullIfNotPresent PullPolicy = "IfNotPresent"
+	// PullNever never pulls the image, which must be present locally, e.g. on offline runners.
+	PullNever PullPolicy = "Never"
+)
+
+// PullImage makes the image ref present locally according to policy.
+// An empty policy is PullAlways.
+func PullImage(ctx context.Context, log *zap.Logger, rt Runtime, ref string, policy PullPolicy) error {
+	present := func() bool {
+		_, _, err := rt.ImageInspectWithRaw(ctx, ref)
+		return err == nil
+	}
+
+	switch policy {
+	case "", PullAlways:
+	case PullIfNotPresent:
+		if present() {
+			return nil
+		}
+	case PullNever:
+		if present() {
+			return nil
+		}
+		return fmt.Errorf("image %s is not present locally and its pull policy is %s", ref, PullNever)
+	default:
+		return fmt.Errorf("unknown pull policy %q of image %s", policy, ref)
+	}
+
+	rc, err := rt.ImagePull(ctx, ref, types.ImagePullOptions{})
+	if err == nil {
+		err = readJSONMessages(log, rc)
+		_ = rc.Close()
+	}
+	if err != nil {
+		if policy != PullIfNotPresent && present() {
+			log.Warn("Failed to pull image, using the local image", zap.String("image", ref), zap.Error(err))
+			return nil
+		}
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	return nil
+}
+
+// MissingImage is an image that could not be made present locally.
+type MissingImage struct {
+	Ref string
+	Err error
+}
+
+// MissingImagesError aggregates all the images that could not be made present locally,
+// so they can be fixed at once rather than one test run at a time.
+type MissingImagesError struct {
+	Images []MissingImage
+}
+
+func (e *MissingImagesError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d images are not available:", len(e.Images))
+	for _, image := range e.Images {
+		fmt.Fprintf(&b, "\n\t%s: %v", image.Ref, image.Err)
+	}
+	return b.String()
+}
+
+func (e *MissingImagesError) Unwrap() []error {
+	errs := make([]error, len(e.Images))
+	for i, image := range e.Images {
+		errs[i] = image.Err
+	}
+	return errs
+}
@@ -0,0 +1,39 @@
+package dockerutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestPullImage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rt := NewFakeRuntime()
+	log := zaptest.NewLogger(t)
+
+	require.ErrorContains(t, PullImage(ctx, log, rt, "gm:local", PullNever), "pull policy is Never")
+
+	require.NoError(t, PullImage(ctx, log, rt, "gm:local", PullIfNotPresent))
+	require.NoError(t, PullImage(ctx, log, rt, "gm:local", PullNever))
+	require.NoError(t, PullImage(ctx, log, rt, "gm:local", ""))
+
+	require.ErrorContains(t, PullImage(ctx, log, rt, "gm:local", "Sometimes"), "unknown pull policy")
+}
+
+func TestMissingImagesError(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errors.New("not found")
+	err := error(&MissingImagesError{Images: []MissingImage{
+		{Ref: "celestia:local", Err: errNotFound},
+		{Ref: "gm:local", Err: errors.New("offline")},
+	}})
+
+	require.Equal(t, "2 images are not available:\n\tcelestia:local: not found\n\tgm:local: offline", err.Error())
+	require.ErrorIs(t, err, errNotFound)
+}
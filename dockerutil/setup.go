@@ -36,6 +36,9 @@ const (
 
 	// NodeOwnerLabel indicates the logical node owning a particular object (probably a volume).
 	NodeOwnerLabel = LabelPrefix + "node-owner"
+
+	// BuildHashLabel is the hash of the build inputs of an image built by BuildImage.
+	BuildHashLabel = LabelPrefix + "build-hash"
 )
 
 // KeepVolumesOnFailure determines whether volumes associated with a test
@@ -48,15 +51,64 @@ const (
 // is test.KeepDockerVolumesOnFailure(bool).
 var KeepVolumesOnFailure = os.Getenv("IBCTEST_SKIP_FAILURE_CLEANUP") != ""
 
-// DockerSetup returns a new Docker Client and the ID of a configured network, associated with t.
+// DockerSetupOption configures DockerSetup.
+type DockerSetupOption func(*dockerSetupOptions)
+
+type dockerSetupOptions struct {
+	backend Backend
+	host    string
+}
+
+// WithBackend selects the container engine DockerSetup connects to, instead of DefaultBackend.
+func WithBackend(backend Backend) DockerSetupOption {
+	return func(o *dockerSetupOptions) {
+		o.backend = backend
+	}
+}
+
+// WithHost sets the address of the container engine API, e.g. unix:///run/user/1000/podman/podman.sock.
+// By default DOCKER_HOST is used for Docker, and the Podman socket of the current user for Podman.
+func WithHost(host string) DockerSetupOption {
+	return func(o *dockerSetupOptions) {
+		o.host = host
+	}
+}
+
+// DockerSetup returns a new container Runtime and the ID of a configured network, associated with t.
+// The Runtime is a Docker client unless another backend is selected with WithBackend or DefaultBackend.
 //
 // If any part of the setup fails, DockerSetup panics because the test cannot continue.
-func DockerSetup(t DockerSetupTestingT) (*client.Client, string) {
+func DockerSetup(t DockerSetupTestingT, options ...DockerSetupOption) (Runtime, string) {
 	t.Helper()
 
-	cli, err := client.NewClientWithOpts(client.FromEnv)
-	if err != nil {
-		panic(fmt.Errorf("failed to create docker client: %v", err))
+	opts := dockerSetupOptions{backend: DefaultBackend}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	var (
+		rt  Runtime
+		cli *client.Client
+	)
+	switch opts.backend {
+	case BackendDocker:
+		clientOpts := []client.Opt{client.FromEnv}
+		if opts.host != "" {
+			clientOpts = append(clientOpts, client.WithHost(opts.host))
+		}
+		c, err := client.NewClientWithOpts(clientOpts...)
+		if err != nil {
+			panic(fmt.Errorf("failed to create docker client: %v", err))
+		}
+		rt, cli = c, c
+	case BackendPodman:
+		p, err := NewPodmanRuntime(opts.host)
+		if err != nil {
+			panic(fmt.Errorf("failed to create podman client: %v", err))
+		}
+		rt, cli = p, p.Client
+	default:
+		panic(fmt.Errorf("unknown container backend %q", opts.backend))
 	}
 
 	// Clean up docker resources at end of test.
@@ -67,7 +119,7 @@ func DockerSetup(t DockerSetupTestingT) (*client.Client, string) {
 	dockerCleanup(t, cli)()
 
 	name := fmt.Sprintf("e2e-%s", RandLowerCaseLetterString(8))
-	network, err := cli.NetworkCreate(context.TODO(), name, types.NetworkCreate{
+	network, err := rt.NetworkCreate(context.TODO(), name, types.NetworkCreate{
 		CheckDuplicate: true,
 
 		Labels: map[string]string{CleanupLabel: t.Name()},
@@ -76,7 +128,7 @@ func DockerSetup(t DockerSetupTestingT) (*client.Client, string) {
 		panic(fmt.Errorf("failed to create docker network: %v", err))
 	}
 
-	return cli, network.ID
+	return rt, network.ID
 }
 
 // dockerCleanup will clean up Docker containers, networks, and the other various config files generated in testing
@@ -99,7 +151,17 @@ func dockerCleanup(t DockerSetupTestingT, cli *client.Client) func() {
 			return
 		}
 
+		var bundleDir string
+		if t.Failed() {
+			bundleDir = FailureBundleStagingDir(t.Name())
+		}
+
 		for _, c := range cs {
+			if bundleDir != "" {
+				if err := writeContainerLogs(ctx, cli, bundleDir, c); err != nil {
+					t.Logf("Failed to add container logs to failure bundle: %v", err)
+				}
+			}
 			if (t.Failed() && showContainerLogs == "") || showContainerLogs == "always" {
 				logTail := "50"
 				if containerLogTail != "" {
@@ -152,6 +214,16 @@ func dockerCleanup(t DockerSetupTestingT, cli *client.Client) func() {
 			}
 		}
 
+		if bundleDir != "" {
+			if err := os.MkdirAll(bundleDir, 0o755); err != nil {
+				t.Logf("Failed to create failure bundle: %v", err)
+			} else if file, err := zipFailureBundle(t.Name()); err != nil {
+				t.Logf("Failed to write failure bundle: %v", err)
+			} else {
+				t.Logf("Wrote failure bundle to %s", file)
+			}
+		}
+
 		if !keepContainers {
 			pruneVolumesWithRetry(ctx, t, cli)
 			pruneNetworksWithRetry(ctx, t, cli)
@@ -5,11 +5,10 @@ import (
 	"time"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
 )
 
 // StartContainer attempts to start the container with the given ID.
-func StartContainer(ctx context.Context, cli *client.Client, id string) error {
+func StartContainer(ctx context.Context, cli Runtime, id string) error {
 	// add a deadline for the request if the calling context does not provide one
 	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
 		var cancel func()
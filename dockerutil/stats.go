@@ -0,0 +1,123 @@
+package dockerutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"golang.org/x/sync/errgroup"
+)
+
+// ContainerStatsSample is the resource usage of a container at a point in time.
+type ContainerStatsSample struct {
+	ContainerName string
+	When          time.Time
+
+	// CPUPercent is relative to a single CPU, e.g. 150 for one and a half CPUs.
+	CPUPercent float64
+
+	// Memory in bytes, excluding the inactive page cache as docker stats does.
+	MemoryUsage, MemoryLimit uint64
+
+	// Total bytes received and sent over all networks.
+	NetworkRx, NetworkTx uint64
+
+	// Total bytes read from and written to block devices.
+	BlockRead, BlockWrite uint64
+}
+
+// SampleContainerStats samples the resource usage of all the running containers of the test,
+// i.e. the nodes, sidecars and relayers labeled with testName.
+func SampleContainerStats(ctx context.Context, rt Runtime, testName string) ([]ContainerStatsSample, error) {
+	containers, err := rt.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", CleanupLabel+"="+testName)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	samples := make([]ContainerStatsSample, len(containers))
+	var eg errgroup.Group
+	for i, c := range containers {
+		i, c := i, c
+		eg.Go(func() error {
+			sample, err := sampleContainerStats(ctx, rt, c.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get stats of container %s: %w", c.ID, err)
+			}
+			if len(c.Names) > 0 {
+				sample.ContainerName = strings.TrimPrefix(c.Names[0], "/")
+			}
+			samples[i] = sample
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+func sampleContainerStats(ctx context.Context, rt Runtime, containerID string) (ContainerStatsSample, error) {
+	// A non streamed sample includes the previous CPU usage the CPU percentage is computed from.
+	res, err := rt.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return ContainerStatsSample{}, err
+	}
+	defer res.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(res.Body).Decode(&stats); err != nil {
+		return ContainerStatsSample{}, fmt.Errorf("failed to decode stats: %w", err)
+	}
+
+	sample := ContainerStatsSample{
+		ContainerName: strings.TrimPrefix(stats.Name, "/"),
+		When:          stats.Read,
+		CPUPercent:    cpuPercent(stats),
+		MemoryUsage:   stats.MemoryStats.Usage,
+		MemoryLimit:   stats.MemoryStats.Limit,
+	}
+
+	// The inactive page cache is reported as inactive_file on cgroup v2 and total_inactive_file on cgroup v1.
+	for _, k := range []string{"inactive_file", "total_inactive_file"} {
+		if v, ok := stats.MemoryStats.Stats[k]; ok && v < sample.MemoryUsage {
+			sample.MemoryUsage -= v
+			break
+		}
+	}
+
+	for _, n := range stats.Networks {
+		sample.NetworkRx += n.RxBytes
+		sample.NetworkTx += n.TxBytes
+	}
+
+	for _, e := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			sample.BlockRead += e.Value
+		case "write":
+			sample.BlockWrite += e.Value
+		}
+	}
+
+	return sample, nil
+}
+
+func cpuPercent(stats types.StatsJSON) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
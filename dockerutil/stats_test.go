@@ -0,0 +1,44 @@
+package dockerutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSampleContainerStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rt := NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) FakeResult {
+		return FakeResult{Running: true}
+	}
+
+	c := NewContainerLifecycle(zaptest.NewLogger(t), rt, "node")
+	resources := ContainerResources{CPUs: 1.5, Memory: 512 << 20}
+	require.NoError(t, c.CreateContainer(ctx, t.Name(), "network", fakeImage{}, nil, nil, "node", []string{"simd", "start"}, resources))
+
+	inspect, err := rt.ContainerInspect(ctx, c.ContainerID())
+	require.NoError(t, err)
+	require.Equal(t, int64(1.5e9), inspect.HostConfig.NanoCPUs)
+
+	// Only running containers are sampled.
+	samples, err := SampleContainerStats(ctx, rt, t.Name())
+	require.NoError(t, err)
+	require.Empty(t, samples)
+
+	require.NoError(t, c.StartContainer(ctx))
+
+	samples, err = SampleContainerStats(ctx, rt, t.Name())
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	require.Equal(t, "node", samples[0].ContainerName)
+	require.Equal(t, uint64(512<<20), samples[0].MemoryLimit)
+
+	samples, err = SampleContainerStats(ctx, rt, "other")
+	require.NoError(t, err)
+	require.Empty(t, samples)
+}
@@ -25,11 +25,21 @@ func GetHostPort(cont types.ContainerJSON, portID string) string {
 		return ""
 	}
 
-	ip := m[0].HostIP
-	if ip == "0.0.0.0" {
+	// The engine may publish a port on IPv6 too; prefer the IPv4 binding.
+	b := m[0]
+	for _, pb := range m {
+		if ip := net.ParseIP(pb.HostIP); ip == nil || ip.To4() != nil {
+			b = pb
+			break
+		}
+	}
+
+	ip := b.HostIP
+	switch ip {
+	case "", "0.0.0.0", "::":
 		ip = "127.0.0.1"
 	}
-	return net.JoinHostPort(ip, m[0].HostPort)
+	return net.JoinHostPort(ip, b.HostPort)
 }
 
 // Ensure that the global RNG is seeded when this package is imported.
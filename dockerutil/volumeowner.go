@@ -7,7 +7,6 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"go.uber.org/zap"
 )
 
@@ -15,7 +14,7 @@ import (
 type VolumeOwnerOptions struct {
 	Log *zap.Logger
 
-	Client *client.Client
+	Client Runtime
 
 	VolumeName string
 	ChainName  string
@@ -25,6 +24,7 @@ type VolumeOwnerOptions struct {
 }
 
 // SetVolumeOwner configures the owner of a volume to match the default user in the supplied image reference.
+// The volume is the host path bound by the nodes, which Podman does not chown either, see PodmanRuntime.
 func SetVolumeOwner(ctx context.Context, opts VolumeOwnerOptions) error {
 	owner := opts.UidGid
 	if owner == "" {
@@ -43,7 +43,7 @@ func SetVolumeOwner(ctx context.Context, opts VolumeOwnerOptions) error {
 	cc, err := opts.Client.ContainerCreate(
 		ctx,
 		&container.Config{
-			Image: busyboxRef, // Using busybox image which has chown and chmod.
+			Image: BusyboxRef, // Using busybox image which has chown and chmod.
 
 			Entrypoint: []string{"sh", "-c"},
 			Cmd: []string{
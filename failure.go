@@ -0,0 +1,115 @@
+package rollupe2etesting
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/testreporter"
+)
+
+// failureArtifactsTimeout bounds the time spent collecting the artifacts of a failed test.
+const failureArtifactsTimeout = 2 * time.Minute
+
+// WriteFailureBundles sets the directory a zip of debugging artifacts is written to when a test fails,
+// named after the test. An empty dir disables failure bundles.
+//
+// The value is empty by default, but can be initialized by setting the
+// environment variable IBCTEST_FAILURE_BUNDLE_DIR.
+// The bundle holds the full logs of every container of the test, and the network artifacts
+// added by Setup.TrackFailureArtifacts.
+func WriteFailureBundles(dir string) {
+	dockerutil.FailureBundleDir = dir
+}
+
+// TrackFailureArtifacts adds the artifacts of the network of s to the failure bundle of t, when t fails:
+// the config directory and genesis of every node, the exported state of every chain, whose full node is left stopped,
+// the config.yaml of every relayer, the block database and the messages of t reported to rep.
+// Rep may be nil.
+//
+// TrackFailureArtifacts must be called after DockerSetup,
+// so that the artifacts are collected before the containers are removed and the bundle is zipped.
+func (s *Setup) TrackFailureArtifacts(t dockerutil.DockerSetupTestingT, opts InterchainBuildOptions, rep *testreporter.Reporter) {
+	t.Cleanup(func() {
+		dir := dockerutil.FailureBundleStagingDir(t.Name())
+		if dir == "" || !t.Failed() {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), failureArtifactsTimeout)
+		defer cancel()
+
+		for _, err := range s.collectFailureArtifacts(ctx, dir, opts, rep, t.Name()) {
+			t.Logf("Failed to add artifact to failure bundle: %v", err)
+		}
+	})
+}
+
+// collectFailureArtifacts writes the artifacts of the network into dir.
+// It collects as many as possible, returning the errors met.
+func (s *Setup) collectFailureArtifacts(ctx context.Context, dir string, opts InterchainBuildOptions, rep *testreporter.Reporter, testName string) []error {
+	var errs []error
+
+	for c := range s.chains {
+		chainID := c.Config().ChainID
+		chainDir := filepath.Join(dir, "chains", chainID)
+		if err := os.MkdirAll(chainDir, 0o755); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if ac, ok := c.(ibc.ArtifactCollector); ok {
+			if err := ac.CollectArtifacts(ctx, chainDir); err != nil {
+				errs = append(errs, fmt.Errorf("chain %s: %w", chainID, err))
+			}
+			if err := exportChainState(ctx, ac, filepath.Join(chainDir, "export.json")); err != nil {
+				errs = append(errs, fmt.Errorf("failed to export state of chain %s: %w", chainID, err))
+			}
+		}
+	}
+
+	for r, name := range s.relayers {
+		src := filepath.Join(s.relayerHostHomeDir(r), "config", "config.yaml")
+		dst := filepath.Join(dir, "relayers", name, "config.yaml")
+		if err := copyArtifact(src, dst); err != nil {
+			errs = append(errs, fmt.Errorf("relayer %s: %w", name, err))
+		}
+	}
+
+	if opts.BlockDatabaseFile != "" {
+		if err := copyArtifact(opts.BlockDatabaseFile, filepath.Join(dir, filepath.Base(opts.BlockDatabaseFile))); err != nil {
+			errs = append(errs, fmt.Errorf("block database: %w", err))
+		}
+	}
+
+	if rep != nil {
+		if err := os.WriteFile(filepath.Join(dir, "report.json"), rep.TestMessagesJSON(testName), 0o644); err != nil {
+			errs = append(errs, fmt.Errorf("report: %w", err))
+		}
+	}
+
+	return errs
+}
+
+func exportChainState(ctx context.Context, ac ibc.ArtifactCollector, file string) error {
+	state, err := ac.ExportCommittedState(ctx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(state), 0o644)
+}
+
+func copyArtifact(src, dst string) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, content, 0o644)
+}
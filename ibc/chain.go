@@ -4,7 +4,7 @@ import (
 	"context"
 
 	"cosmossdk.io/math"
-	"github.com/docker/docker/client"
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 )
 
 type Chain interface {
@@ -12,7 +12,7 @@ type Chain interface {
 	Config() ChainConfig
 
 	// Initialize initializes node structs so that things like initializing keys can be done before starting the chain
-	Initialize(ctx context.Context, testName string, cli *client.Client, networkID string) error
+	Initialize(ctx context.Context, testName string, cli dockerutil.Runtime, networkID string) error
 
 	// Start sets up everything needed (validators, gentx, fullnodes, peering, additional accounts) for Chain to start from genesis.
 	Start(testName string, ctx context.Context, additionalGenesisWallets ...WalletData) error
@@ -138,6 +138,29 @@ type RollApp interface {
 	SetDABlockHeight(string)
 }
 
+// ArtifactCollector is implemented by chains able to add the files of their nodes to a failure bundle,
+// see rollupe2etesting.WriteFailureBundles.
+type ArtifactCollector interface {
+	// CollectArtifacts copies the config directory of every node, genesis included, into dir.
+	CollectArtifacts(ctx context.Context, dir string) error
+	// ExportCommittedState exports the chain state at the last committed height,
+	// without racing the blocks committed meanwhile.
+	ExportCommittedState(ctx context.Context) (string, error)
+}
+
+// HostAddresses are the addresses of a node reachable from the host, see Chain.GetHostRPCAddress.
+type HostAddresses struct {
+	RPC, GRPC, API string
+}
+
+// HostAddressNotifier is implemented by chains whose host addresses change when a node starts
+// on new host ports, see dockerutil.PortAllocationDocker.
+type HostAddressNotifier interface {
+	// OnHostAddressesChange registers fn, called with the previous and new host addresses of a node
+	// that started on new host ports. An error of fn fails the start of the node.
+	OnHostAddressesChange(fn func(ctx context.Context, old, new HostAddresses) error)
+}
+
 // TransferOptions defines the options for an IBC packet transfer.
 type TransferOptions struct {
 	Timeout *IBCTimeout
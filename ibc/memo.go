@@ -0,0 +1,58 @@
+package ibc
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// PacketMetadata is the memo understood by the packet-forward middleware.
+type PacketMetadata struct {
+	Forward *ForwardMetadata `json:"forward"`
+}
+
+// ForwardMetadata describes where the packet-forward middleware sends a packet next.
+type ForwardMetadata struct {
+	Receiver string `json:"receiver"`
+	Port     string `json:"port"`
+	Channel  string `json:"channel"`
+	// Timeout is a duration string, e.g. "10m". Defaults to the middleware's timeout if empty.
+	Timeout string `json:"timeout,omitempty"`
+	// Retries defaults to the middleware's retry count if nil.
+	Retries *uint8          `json:"retries,omitempty"`
+	Next    *PacketMetadata `json:"next,omitempty"`
+}
+
+// ForwardMemo returns a packet-forward middleware memo that routes a transfer through hops in order.
+// Any Next field set on the hops is overwritten.
+func ForwardMemo(hops ...ForwardMetadata) (string, error) {
+	if len(hops) == 0 {
+		return "", errors.New("at least one hop is required")
+	}
+
+	var next *PacketMetadata
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		hop.Next = next
+		next = &PacketMetadata{Forward: &hop}
+	}
+
+	memo, err := json.Marshal(next)
+	if err != nil {
+		return "", err
+	}
+	return string(memo), nil
+}
+
+// WasmHookMemo returns an ibc-hooks memo that executes msg on contract once the transfer is received.
+func WasmHookMemo(contract string, msg any) (string, error) {
+	memo, err := json.Marshal(map[string]any{
+		"wasm": map[string]any{
+			"contract": contract,
+			"msg":      msg,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(memo), nil
+}
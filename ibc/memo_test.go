@@ -0,0 +1,49 @@
+package ibc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardMemo(t *testing.T) {
+	t.Parallel()
+
+	t.Run("multi hop", func(t *testing.T) {
+		memo, err := ForwardMemo(
+			ForwardMetadata{Receiver: "dym1hub", Port: "transfer", Channel: "channel-1"},
+			ForwardMetadata{Receiver: "rol1dst", Port: "transfer", Channel: "channel-2", Timeout: "10m"},
+		)
+		require.NoError(t, err)
+
+		const want = `{
+  "forward": {
+    "receiver": "dym1hub",
+    "port": "transfer",
+    "channel": "channel-1",
+    "next": {
+      "forward": {
+        "receiver": "rol1dst",
+        "port": "transfer",
+        "channel": "channel-2",
+        "timeout": "10m"
+      }
+    }
+  }
+}`
+		require.JSONEq(t, want, memo)
+	})
+
+	t.Run("no hops", func(t *testing.T) {
+		_, err := ForwardMemo()
+		require.Error(t, err)
+	})
+}
+
+func TestWasmHookMemo(t *testing.T) {
+	t.Parallel()
+
+	memo, err := WasmHookMemo("rol1contract", map[string]any{"increment": map[string]any{}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"wasm":{"contract":"rol1contract","msg":{"increment":{}}}}`, memo)
+}
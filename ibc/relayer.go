@@ -2,6 +2,7 @@ package ibc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,9 +12,9 @@ import (
 )
 
 // Relayer represents an instance of a relayer that can be support IBC.
-// Built-in implementations are run through Docker,
-// but they could exec out to external processes
-// or even be implemented in-process in Go.
+// Built-in implementations are run through Docker, or exec out to a relayer binary installed on the host,
+// see CosmosRlyLocal. Implementations could even be in-process in Go,
+// but none is built in, since the cosmos/relayer library is not a dependency of this module.
 //
 // All of the methods on Relayer accept a RelayerExecReporter.
 // It is intended that Relayer implementations will call the reporters' TrackRelayerExec method
@@ -44,6 +45,10 @@ type Relayer interface {
 	// update path channel filter
 	UpdatePath(ctx context.Context, rep RelayerExecReporter, pathName string, filter ChannelFilter) error
 
+	// SetPathIDs points an existing path at clients and connections created by another relayer,
+	// so that both relayers relay the same channels.
+	SetPathIDs(ctx context.Context, rep RelayerExecReporter, pathName string, ids PathIDs) error
+
 	// update clients, such as after new genesis
 	UpdateClients(ctx context.Context, rep RelayerExecReporter, pathName string) error
 
@@ -64,13 +69,33 @@ type Relayer interface {
 	// StopRelayer stops a relayer that started work through StartRelayer.
 	StopRelayer(ctx context.Context, rep RelayerExecReporter) error
 
+	// PauseRelayer suspends a relayer started through StartRelayer without stopping it,
+	// leaving packets in flight until ResumeRelayer is called.
+	PauseRelayer(ctx context.Context, rep RelayerExecReporter) error
+
+	// ResumeRelayer resumes a relayer suspended by PauseRelayer.
+	ResumeRelayer(ctx context.Context, rep RelayerExecReporter) error
+
 	// Flush flushes any outstanding packets and then returns.
 	Flush(ctx context.Context, rep RelayerExecReporter, pathName string, channelID string) error
 
+	// RelayPackets relays the outstanding packets sent on channelID of the source chain of the path,
+	// or only the given sequences if any, and then returns.
+	// Acknowledgements are left pending, to be relayed by RelayAcknowledgements.
+	RelayPackets(ctx context.Context, rep RelayerExecReporter, pathName, channelID string, sequences ...uint64) error
+
+	// RelayAcknowledgements relays the outstanding acknowledgements of packets sent on channelID
+	// of the source chain of the path, and then returns.
+	RelayAcknowledgements(ctx context.Context, rep RelayerExecReporter, pathName, channelID string) error
+
 	// CreateClients performs the client handshake steps necessary for creating a light client
 	// on src that tracks the state of dst, and a light client on dst that tracks the state of src.
 	CreateClients(ctx context.Context, rep RelayerExecReporter, pathName string, opts CreateClientOptions) error
 
+	// CreateClient creates a light client on src that tracks the state of dst, recording it on the path.
+	// Unlike CreateClients, no client is created on dst.
+	CreateClient(ctx context.Context, rep RelayerExecReporter, srcChainID, dstChainID, pathName string, opts CreateClientOptions) error
+
 	// CreateConnections performs the connection handshake steps necessary for creating a connection
 	// between the src and dst chains.
 	CreateConnections(ctx context.Context, rep RelayerExecReporter, pathName string) error
@@ -82,12 +107,25 @@ type Relayer interface {
 	// CreateChannel creates a channel on the given path with the provided options.
 	CreateChannel(ctx context.Context, rep RelayerExecReporter, pathName string, opts CreateChannelOptions) error
 
+	// LinkConnection creates clients with the provided options, if they do not exist yet,
+	// and a connection on the given path, without opening any channel.
+	LinkConnection(ctx context.Context, rep RelayerExecReporter, pathName string, clientOpts CreateClientOptions) error
+
+	// CloseChannel closes the channel with the given ID and port on the source chain of the path,
+	// and relays the close to the counterparty.
+	CloseChannel(ctx context.Context, rep RelayerExecReporter, pathName, channelID, portID string) error
+
+	// UpgradeChannel initiates and relays the upgrade handshake of the channel with the given ID and port
+	// on the source chain of the path. Relayers or chains that do not support channel upgrades
+	// return an error wrapping ErrChannelUpgradeUnsupported.
+	UpgradeChannel(ctx context.Context, rep RelayerExecReporter, pathName, channelID, portID string, opts ChannelUpgradeOptions) error
+
 	// UseDockerNetwork reports whether the relayer is run in the same docker network as the other chains.
 	//
 	// If false, the relayer will connect to the localhost-exposed ports instead of the docker hosts.
 	//
-	// Relayer implementations provided by the e2e-test module will report true,
-	// but custom implementations may report false.
+	// Docker relayer implementations provided by the e2e-test module report true,
+	// the local relayer running on the host and custom implementations may report false.
 	UseDockerNetwork() bool
 
 	// Exec runs an arbitrary relayer command.
@@ -174,6 +212,56 @@ func GetTransferChannel(ctx context.Context, r Relayer, rep RelayerExecReporter,
 	return srcChan, nil
 }
 
+// PathIDs identifies the clients and connections a path relays over.
+type PathIDs struct {
+	SrcClientID, SrcConnectionID string
+	DstClientID, DstConnectionID string
+}
+
+// GetPathIDs returns the clients and connections r relays between two chains,
+// assuming only one client and one connection exist on the source chain for the destination chain.
+func GetPathIDs(ctx context.Context, r Relayer, rep RelayerExecReporter, srcChainID, dstChainID string) (PathIDs, error) {
+	srcClients, err := r.GetClients(ctx, rep, srcChainID)
+	if err != nil {
+		return PathIDs{}, fmt.Errorf("failed to get clients on source chain: %w", err)
+	}
+
+	var ids PathIDs
+	for _, client := range srcClients {
+		if client.ClientState.ChainID == dstChainID {
+			if ids.SrcClientID != "" {
+				return PathIDs{}, fmt.Errorf("found multiple clients on %s tracking %s", srcChainID, dstChainID)
+			}
+			ids.SrcClientID = client.ClientID
+		}
+	}
+	if ids.SrcClientID == "" {
+		return PathIDs{}, fmt.Errorf("unable to find client on %s tracking %s", srcChainID, dstChainID)
+	}
+
+	srcConnections, err := r.GetConnections(ctx, rep, srcChainID)
+	if err != nil {
+		return PathIDs{}, fmt.Errorf("failed to get connections on source chain: %w", err)
+	}
+
+	for _, connection := range srcConnections {
+		if connection.ClientID != ids.SrcClientID || connection.Counterparty == nil {
+			continue
+		}
+		if ids.SrcConnectionID != "" {
+			return PathIDs{}, fmt.Errorf("found multiple connections on %s for client %s", srcChainID, ids.SrcClientID)
+		}
+		ids.SrcConnectionID = connection.ID
+		ids.DstClientID = connection.Counterparty.ClientId
+		ids.DstConnectionID = connection.Counterparty.ConnectionId
+	}
+	if ids.SrcConnectionID == "" {
+		return PathIDs{}, fmt.Errorf("unable to find connection on %s for client %s", srcChainID, ids.SrcClientID)
+	}
+
+	return ids, nil
+}
+
 // RelyaerExecResult holds the details of a call to Relayer.Exec.
 type RelayerExecResult struct {
 	// This type is a redeclaration of dockerutil.ContainerExecResult.
@@ -204,6 +292,10 @@ type CreateChannelOptions struct {
 	Order Order
 
 	Version string
+
+	// Override creates a new channel even if the path already has an open channel
+	// on the same ports, e.g. to reopen an ordered channel closed by a packet timeout.
+	Override bool
 }
 
 // DefaultChannelOpts returns the default settings for creating an ics20 fungible token transfer channel.
@@ -216,6 +308,17 @@ func DefaultChannelOpts() CreateChannelOptions {
 	}
 }
 
+// OrderedChannelOpts returns the settings for creating an ordered channel between
+// the given ports of a custom application, such as an interchain accounts channel.
+func OrderedChannelOpts(srcPort, dstPort, version string) CreateChannelOptions {
+	return CreateChannelOptions{
+		SourcePortName: srcPort,
+		DestPortName:   dstPort,
+		Order:          Ordered,
+		Version:        version,
+	}
+}
+
 // Validate will check that the specified CreateChannelOptions are valid.
 func (opts CreateChannelOptions) Validate() error {
 	switch {
@@ -260,6 +363,20 @@ func (o Order) Validate() error {
 	return chantypes.ErrInvalidChannelOrdering
 }
 
+// ChannelUpgradeOptions contains the proposed fields of an upgraded channel.
+// Empty fields keep the current value of the channel.
+type ChannelUpgradeOptions struct {
+	Version        string
+	Order          Order
+	ConnectionHops []string
+}
+
+// ErrChannelUpgradeUnsupported is returned by Relayer.UpgradeChannel when the channel upgrade handshake
+// cannot be run: it needs ibc-go v8.1 chains, while the chains of this module run ibc-go v7,
+// and a relayer with channel upgrade commands, which the built-in rly release lacks.
+// Tests can skip on it with errors.Is.
+var ErrChannelUpgradeUnsupported = errors.New("channel upgrades are not supported")
+
 // CreateClientOptions contains the configuration for creating a client.
 type CreateClientOptions struct {
 	TrustingPeriod string
@@ -312,6 +429,33 @@ type RelayerExecReporter interface {
 	)
 }
 
+// HostAddressUpdater is implemented by relayers running on the host,
+// whose configuration holds the host addresses of the chains, see HostAddressNotifier.
+type HostAddressUpdater interface {
+	// UpdateHostAddresses replaces the old host addresses of a node with the new ones in the configuration
+	// of the relayer, and restarts the relayer if it is running.
+	UpdateHostAddresses(ctx context.Context, rep RelayerExecReporter, old, new HostAddresses) error
+}
+
+// RelayerLogReporter is optionally implemented by a RelayerExecReporter
+// to receive the log lines of a running relayer as they are produced.
+type RelayerLogReporter interface {
+	TrackRelayerLog(
+		// The name of the docker container of the relayer,
+		// or empty if it did not run in docker.
+		containerName string,
+
+		// The stream the line was written to, "stdout" or "stderr".
+		stream string,
+
+		// The log line, without the trailing newline.
+		line string,
+
+		// When the line was received.
+		when time.Time,
+	)
+}
+
 // NopRelayerExecReporter is a no-op RelayerExecReporter.
 type NopRelayerExecReporter struct{}
 
@@ -0,0 +1,41 @@
+package ibc
+
+import "context"
+
+// NodeSnapshot describes a node of a network snapshot.
+type NodeSnapshot struct {
+	Validator bool
+	Index     int
+
+	// HostName and HomeDir of the node, replaced by those of the restored node in its configuration files.
+	HostName string
+	HomeDir  string
+
+	// Cmd of the node container.
+	Cmd []string
+
+	// Archive is the file holding the home directory of the node, relative to the snapshot directory of the chain.
+	Archive string
+}
+
+// Snapshotter is implemented by chains whose nodes can be archived into a network snapshot and restored from it,
+// see rollupe2etesting.Setup.Snapshot and rollupe2etesting.RestoreSetup.
+type Snapshotter interface {
+	// StopNodes stops the containers of all the nodes, keeping them and their volumes for SnapshotNodes.
+	StopNodes(ctx context.Context) error
+
+	// SnapshotNodes archives the home directory of every node, stopped by StopNodes, into dir.
+	SnapshotNodes(ctx context.Context, dir string) ([]NodeSnapshot, error)
+
+	// StartNodes starts the containers of all the nodes stopped by StopNodes again.
+	StartNodes(ctx context.Context) error
+
+	// DescribeNodes describes the nodes created by Initialize, without archives,
+	// to map the nodes of a snapshot onto them.
+	DescribeNodes() []NodeSnapshot
+
+	// RestoreNodes creates and starts the containers of the nodes created by Initialize
+	// from the snapshot nodes archived in dir. The content of the configuration files and the commands
+	// of the nodes go through rewrite, to refer to the restored hosts.
+	RestoreNodes(ctx context.Context, dir string, nodes []NodeSnapshot, rewrite func(string) string) error
+}
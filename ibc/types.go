@@ -1,6 +1,7 @@
 package ibc
 
 import (
+	"context"
 	"reflect"
 	"strconv"
 
@@ -8,6 +9,8 @@ import (
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/module/testutil"
 	ibcexported "github.com/cosmos/ibc-go/v7/modules/core/03-connection/types"
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"go.uber.org/zap"
 )
 
 // ChainConfig defines the chain parameters requires to run an testnet for a chain.
@@ -54,6 +57,8 @@ type ChainConfig struct {
 	CoinDecimals *int64
 	// Configuration describing additional sidecar processes.
 	SidecarConfigs []SidecarConfig
+	// CPU and memory limits of every node container. Zero values mean no limit.
+	Resources dockerutil.ContainerResources `yaml:"resources"`
 }
 
 func (c ChainConfig) Clone() ChainConfig {
@@ -84,6 +89,8 @@ type SidecarConfig struct {
 	StartCmd         []string
 	PreStart         bool
 	ValidatorProcess bool
+	// CPU and memory limits of the sidecar container. Zero values mean no limit.
+	Resources dockerutil.ContainerResources
 }
 
 func (c ChainConfig) VerifyCoinType() (string, error) {
@@ -206,6 +213,20 @@ type DockerImage struct {
 	Repository string `yaml:"repository"`
 	Version    string `yaml:"version"`
 	UidGid     string `yaml:"uid-gid"`
+	// When set, the image is built from a Dockerfile, e.g. of a source checkout, instead of being pulled.
+	// The build is cached by content hash, see dockerutil.BuildImage.
+	Build *dockerutil.ImageBuild `yaml:"build"`
+	// When the image is pulled. Defaults to dockerutil.PullAlways.
+	PullPolicy dockerutil.PullPolicy `yaml:"pull-policy"`
+}
+
+// Ensure makes the image present for cli: built if it has a build configuration,
+// otherwise pulled according to its pull policy.
+func (i DockerImage) Ensure(ctx context.Context, log *zap.Logger, cli dockerutil.Runtime) error {
+	if i.Build != nil {
+		return dockerutil.BuildImage(ctx, log, cli, i.Ref(), *i.Build)
+	}
+	return dockerutil.PullImage(ctx, log, cli, i.Ref(), i.PullPolicy)
 }
 
 // Ref returns the reference to use when e.g. creating a container.
@@ -278,6 +299,8 @@ type RelayerImplementation int64
 const (
 	CosmosRly RelayerImplementation = iota
 	Hermes
+	// CosmosRlyLocal runs the cosmos relayer binary installed on the host, one process per command, instead of in Docker.
+	CosmosRlyLocal
 )
 
 // ChannelFilter provides the means for either creating an allowlist or a denylist of channels on the src chain
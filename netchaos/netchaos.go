@@ -0,0 +1,391 @@
+// Package netchaos injects network faults between the containers of a test network:
+// partitions by disconnecting containers from the network, latency and packet loss,
+// and blocked traffic between pairs of containers.
+//
+// Latency, loss and blocking are applied by a sidecar container sharing the network namespace of the target,
+// so the images of the chains and relayers do not need tc or iptables.
+package netchaos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"go.uber.org/zap"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+)
+
+// DefaultSidecarImage is the image of the sidecars applying latency, loss and blocking.
+// It must provide sh, ip, tc and iptables.
+var DefaultSidecarImage = ibc.DockerImage{
+	Repository: "nicolaka/netshoot",
+	Version:    "v0.11",
+}
+
+// Target is a set of containers faults are injected into, e.g. the nodes of a chain or a relayer.
+type Target interface {
+	ContainerIDs() []string
+}
+
+// Containers is a Target of the containers with the given IDs.
+type Containers []string
+
+func (c Containers) ContainerIDs() []string {
+	return c
+}
+
+// Impairment degrades the traffic sent by a container.
+type Impairment struct {
+	// Latency added to every packet, and its random variation.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// Percentage of packets dropped, between 0 and 100.
+	Loss float64
+}
+
+func (i Impairment) netemArgs() []string {
+	var args []string
+	if i.Latency > 0 {
+		args = append(args, "delay", netemTime(i.Latency))
+		if i.Jitter > 0 {
+			args = append(args, netemTime(i.Jitter))
+		}
+	}
+	if i.Loss > 0 {
+		args = append(args, "loss", strconv.FormatFloat(i.Loss, 'f', -1, 64)+"%")
+	}
+	return args
+}
+
+func netemTime(d time.Duration) string {
+	return strconv.FormatInt(d.Microseconds(), 10) + "us"
+}
+
+// Network injects faults into the containers attached to a docker network,
+// and keeps track of them so they can be reverted with Heal.
+type Network struct {
+	log       *zap.Logger
+	rt        dockerutil.Runtime
+	testName  string
+	networkID string
+
+	// SidecarImage defaults to DefaultSidecarImage.
+	SidecarImage ibc.DockerImage
+
+	mu           sync.Mutex
+	sidecars     map[string]string
+	disconnected map[string]bool
+	impaired     map[string]bool
+	blocked      map[blockRule]bool
+}
+
+// blockRule drops the traffic between a container and the IP address a peer had when it was blocked.
+type blockRule struct {
+	containerID string
+	peerID      string
+	peerIP      string
+}
+
+// NewNetwork returns a Network injecting faults into the containers attached to networkID.
+func NewNetwork(log *zap.Logger, rt dockerutil.Runtime, testName, networkID string) *Network {
+	return &Network{
+		log:       log,
+		rt:        rt,
+		testName:  testName,
+		networkID: networkID,
+
+		SidecarImage: DefaultSidecarImage,
+
+		sidecars:     make(map[string]string),
+		disconnected: make(map[string]bool),
+		impaired:     make(map[string]bool),
+		blocked:      make(map[blockRule]bool),
+	}
+}
+
+// Disconnect partitions the containers of targets from the network, as if their host went offline.
+// Containers keep running and reach each other only through other networks, if any.
+func (n *Network) Disconnect(ctx context.Context, targets ...Target) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, id := range containerIDs(targets) {
+		if n.disconnected[id] {
+			continue
+		}
+		if err := n.rt.NetworkDisconnect(ctx, n.networkID, id, true); err != nil {
+			return fmt.Errorf("failed to disconnect container %s: %w", id, err)
+		}
+		n.disconnected[id] = true
+		// The interface of the network goes away with its impairment.
+		delete(n.impaired, id)
+		n.log.Info("Disconnected container from network", zap.String("container_id", id), zap.String("network_id", n.networkID))
+	}
+	return nil
+}
+
+// Reconnect attaches the containers of targets disconnected by Disconnect to the network again.
+// Their IP addresses may change, their names still resolve.
+func (n *Network) Reconnect(ctx context.Context, targets ...Target) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, id := range containerIDs(targets) {
+		if err := n.reconnect(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Network) reconnect(ctx context.Context, id string) error {
+	if !n.disconnected[id] {
+		return nil
+	}
+	if err := n.rt.NetworkConnect(ctx, n.networkID, id, nil); err != nil {
+		return fmt.Errorf("failed to reconnect container %s: %w", id, err)
+	}
+	delete(n.disconnected, id)
+	n.log.Info("Reconnected container to network", zap.String("container_id", id), zap.String("network_id", n.networkID))
+	return nil
+}
+
+// Impair applies imp to the traffic sent by the containers of targets on the network,
+// replacing any previous impairment.
+func (n *Network) Impair(ctx context.Context, imp Impairment, targets ...Target) error {
+	args := imp.netemArgs()
+	if len(args) == 0 {
+		return n.ClearImpairment(ctx, targets...)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, id := range containerIDs(targets) {
+		script := `tc qdisc replace dev "$(` + interfaceCmd + `)" root netem ` + strings.Join(args, " ")
+		if err := n.tcExec(ctx, id, script); err != nil {
+			return fmt.Errorf("failed to impair container %s: %w", id, err)
+		}
+		n.impaired[id] = true
+	}
+	return nil
+}
+
+// ClearImpairment removes the impairment of the containers of targets.
+func (n *Network) ClearImpairment(ctx context.Context, targets ...Target) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, id := range containerIDs(targets) {
+		if err := n.clearImpairment(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Network) clearImpairment(ctx context.Context, id string) error {
+	if !n.impaired[id] {
+		return nil
+	}
+	if err := n.tcExec(ctx, id, `tc qdisc del dev "$(`+interfaceCmd+`)" root`); err != nil {
+		return fmt.Errorf("failed to clear impairment of container %s: %w", id, err)
+	}
+	delete(n.impaired, id)
+	return nil
+}
+
+// Block drops all traffic between the containers of a and the containers of b on the network,
+// while both keep reaching other containers.
+func (n *Network) Block(ctx context.Context, a, b Target) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, peer := range b.ContainerIDs() {
+		peerIP, err := n.ipAddress(ctx, peer)
+		if err != nil {
+			return err
+		}
+		for _, id := range a.ContainerIDs() {
+			rule := blockRule{containerID: id, peerID: peer, peerIP: peerIP}
+			if n.blocked[rule] {
+				continue
+			}
+			if err := n.sidecarExec(ctx, id, rule.script("-I")); err != nil {
+				return fmt.Errorf("failed to block %s from container %s: %w", peerIP, id, err)
+			}
+			n.blocked[rule] = true
+		}
+	}
+	return nil
+}
+
+// Unblock restores the traffic between the containers of a and b blocked by Block.
+func (n *Network) Unblock(ctx context.Context, a, b Target) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	peers := make(map[string]bool)
+	for _, peer := range b.ContainerIDs() {
+		peers[peer] = true
+	}
+	for _, id := range a.ContainerIDs() {
+		for rule := range n.blocked {
+			if rule.containerID == id && peers[rule.peerID] {
+				if err := n.unblock(ctx, rule); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (n *Network) unblock(ctx context.Context, rule blockRule) error {
+	if err := n.sidecarExec(ctx, rule.containerID, rule.script("-D")); err != nil {
+		return fmt.Errorf("failed to unblock %s from container %s: %w", rule.peerIP, rule.containerID, err)
+	}
+	delete(n.blocked, rule)
+	return nil
+}
+
+func (r blockRule) script(op string) string {
+	return fmt.Sprintf("iptables %[1]s INPUT -s %[2]s -j DROP && iptables %[1]s OUTPUT -d %[2]s -j DROP", op, r.peerIP)
+}
+
+// Heal reverts all the faults injected by n: blocked traffic, impairments and partitions,
+// and removes the sidecars.
+func (n *Network) Heal(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for id := range n.disconnected {
+		if err := n.reconnect(ctx, id); err != nil {
+			return err
+		}
+	}
+	for rule := range n.blocked {
+		if err := n.unblock(ctx, rule); err != nil {
+			return err
+		}
+	}
+	for id := range n.impaired {
+		if err := n.clearImpairment(ctx, id); err != nil {
+			return err
+		}
+	}
+	for id, sidecarID := range n.sidecars {
+		if err := n.rt.ContainerRemove(ctx, sidecarID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("failed to remove network sidecar of container %s: %w", id, err)
+		}
+		delete(n.sidecars, id)
+	}
+	return nil
+}
+
+// interfaceCmd prints the name of the network interface with the IP address in $IP.
+const interfaceCmd = `ip -o -4 addr show | awk -v ip="$IP" '$4 ~ "^"ip"/" {print $2; exit}'`
+
+// tcExec runs script in the sidecar of the container with id, with $IP set to its address on the network.
+// n.mu must be held.
+func (n *Network) tcExec(ctx context.Context, id, script string) error {
+	ip, err := n.ipAddress(ctx, id)
+	if err != nil {
+		return err
+	}
+	return n.sidecarExec(ctx, id, script, "IP="+ip)
+}
+
+// sidecarExec runs script in the sidecar of the container with id. n.mu must be held.
+func (n *Network) sidecarExec(ctx context.Context, id, script string, env ...string) error {
+	sidecarID, err := n.sidecar(ctx, id)
+	if err != nil {
+		return err
+	}
+	res := dockerutil.ExecInContainer(ctx, n.rt, sidecarID, "netchaos-"+shortID(id), []string{"sh", "-c", script}, env)
+	return res.Err
+}
+
+// sidecar returns the ID of the sidecar sharing the network namespace of the container with id,
+// starting it if needed. n.mu must be held.
+func (n *Network) sidecar(ctx context.Context, id string) (string, error) {
+	if sidecarID, ok := n.sidecars[id]; ok {
+		return sidecarID, nil
+	}
+
+	ref := n.SidecarImage.Ref()
+	if _, _, err := n.rt.ImageInspectWithRaw(ctx, ref); err != nil {
+		rc, err := n.rt.ImagePull(ctx, ref, types.ImagePullOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to pull image %s: %w", ref, err)
+		}
+		_, _ = io.Copy(io.Discard, rc)
+		_ = rc.Close()
+	}
+
+	cc, err := n.rt.ContainerCreate(
+		ctx,
+		&container.Config{
+			Image:      ref,
+			Entrypoint: []string{"sleep"},
+			Cmd:        []string{"infinity"},
+			User:       dockerutil.GetRootUserString(),
+			Labels:     map[string]string{dockerutil.CleanupLabel: n.testName},
+		},
+		&container.HostConfig{
+			NetworkMode: container.NetworkMode("container:" + id),
+			CapAdd:      []string{"NET_ADMIN"},
+		},
+		nil, // Shares the network of the target.
+		nil,
+		fmt.Sprintf("netchaos-%s-%s", shortID(id), dockerutil.RandLowerCaseLetterString(5)),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create network sidecar of container %s: %w", id, err)
+	}
+	if err := dockerutil.StartContainer(ctx, n.rt, cc.ID); err != nil {
+		return "", fmt.Errorf("failed to start network sidecar of container %s: %w", id, err)
+	}
+	n.sidecars[id] = cc.ID
+	return cc.ID, nil
+}
+
+// ipAddress returns the IP address of the container with id on the network.
+func (n *Network) ipAddress(ctx context.Context, id string) (string, error) {
+	c, err := n.rt.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container %s: %w", id, err)
+	}
+	if c.NetworkSettings != nil {
+		for _, e := range c.NetworkSettings.Networks {
+			if e.NetworkID == n.networkID && e.IPAddress != "" {
+				return e.IPAddress, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("container %s is not connected to network %s", id, n.networkID)
+}
+
+func containerIDs(targets []Target) []string {
+	var ids []string
+	for _, t := range targets {
+		ids = append(ids, t.ContainerIDs()...)
+	}
+	return ids
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,65 @@
+package netchaos
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+)
+
+func TestNetwork(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var (
+		mu      sync.Mutex
+		scripts []string
+	)
+	rt := dockerutil.NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		if cmd[0] == "sleep" {
+			return dockerutil.FakeResult{Running: true}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		scripts = append(scripts, cmd[len(cmd)-1])
+		return dockerutil.FakeResult{}
+	}
+
+	newContainer := func(name string) string {
+		cc, err := rt.ContainerCreate(ctx, &container.Config{}, nil, &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{"net": {}},
+		}, nil, name)
+		require.NoError(t, err)
+		return cc.ID
+	}
+	a, b := Containers{newContainer("a")}, Containers{newContainer("b")}
+
+	n := NewNetwork(zaptest.NewLogger(t), rt, t.Name(), "net")
+
+	require.NoError(t, n.Block(ctx, a, b))
+	require.NoError(t, n.Impair(ctx, Impairment{Latency: 200 * time.Millisecond, Loss: 2.5}, b))
+	require.NoError(t, n.Disconnect(ctx, a))
+	_, err := n.ipAddress(ctx, a[0])
+	require.Error(t, err)
+
+	require.NoError(t, n.Heal(ctx))
+	_, err = n.ipAddress(ctx, a[0])
+	require.NoError(t, err)
+
+	require.Len(t, scripts, 4)
+	require.True(t, strings.HasPrefix(scripts[0], "iptables -I INPUT -s "))
+	require.True(t, strings.HasSuffix(scripts[1], "root netem delay 200000us loss 2.5%"))
+	require.True(t, strings.HasPrefix(scripts[2], "iptables -D INPUT -s "))
+	require.True(t, strings.HasPrefix(scripts[3], "tc qdisc del "))
+	require.Empty(t, n.sidecars)
+}
@@ -0,0 +1,84 @@
+package rollupe2etesting
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/decentrio/rollup-e2e-testing/blockdb"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/testutil"
+)
+
+// packetRelayerBlocks is the number of blocks searched for the receipt of a packet.
+const packetRelayerBlocks = 20
+
+// PacketRelayer returns the name of the relayer that delivered packet to dst,
+// searching the blocks of dst from startHeight for the transaction that received it.
+// This is mostly useful when several relayers race on the same path, see InterchainLink.SharedRelayers.
+// The relayer is identified by the signer of the transaction, so dst must implement blockdb.TxFinder.
+func (s *Setup) PacketRelayer(ctx context.Context, dst ibc.Chain, startHeight int64, packet ibc.Packet) (string, error) {
+	finder, ok := dst.(blockdb.TxFinder)
+	if !ok {
+		return "", fmt.Errorf("chain %s cannot find transactions", dst.Config().ChainID)
+	}
+
+	poll := func(ctx context.Context, height int64) ([]string, error) {
+		txs, err := finder.FindTxs(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range txs {
+			if signers, ok := packetReceivers(tx.Events, packet); ok {
+				return signers, nil
+			}
+		}
+		return nil, testutil.ErrNotFound
+	}
+
+	bp := testutil.BlockPoller[[]string]{CurrentHeight: dst.Height, PollFunc: poll}
+	signers, err := bp.DoPoll(ctx, startHeight, startHeight+packetRelayerBlocks)
+	if err != nil {
+		return "", fmt.Errorf("failed to find receipt of packet %d on %s: %w", packet.Sequence, dst.Config().ChainID, err)
+	}
+
+	for r, name := range s.relayers {
+		wallet, ok := r.GetWallet(dst.Config().ChainID)
+		if !ok {
+			continue
+		}
+		for _, signer := range signers {
+			if signer == wallet.FormattedAddress() {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("packet %d on %s was received by %v, which is not a known relayer", packet.Sequence, dst.Config().ChainID, signers)
+}
+
+// packetReceivers returns the signers of a transaction if its events contain the receipt of packet.
+func packetReceivers(events []blockdb.Event, packet ibc.Packet) ([]string, bool) {
+	var (
+		received bool
+		signers  []string
+	)
+	for _, e := range events {
+		switch e.Type {
+		case "recv_packet":
+			if e.Attribute("packet_sequence") == strconv.FormatUint(packet.Sequence, 10) &&
+				e.Attribute("packet_dst_port") == packet.DestPort &&
+				e.Attribute("packet_dst_channel") == packet.DestChannel {
+				received = true
+			}
+		case "tx":
+			if payer := e.Attribute("fee_payer"); payer != "" {
+				signers = append(signers, payer)
+			}
+		case "message":
+			if sender := e.Attribute("sender"); sender != "" {
+				signers = append(signers, sender)
+			}
+		}
+	}
+	return signers, received
+}
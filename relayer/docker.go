@@ -4,17 +4,17 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/netchaos"
 	"github.com/decentrio/rollup-e2e-testing/testutil"
 	"github.com/docker/docker/api/types"
 	volumetypes "github.com/docker/docker/api/types/volume"
-	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 	"go.uber.org/zap"
 )
@@ -32,7 +32,7 @@ type DockerRelayer struct {
 	c RelayerCommander
 
 	networkID  string
-	client     *client.Client
+	client     dockerutil.Runtime
 	volumeName string
 
 	testName string
@@ -40,9 +40,27 @@ type DockerRelayer struct {
 	customImage *ibc.DockerImage
 	pullImage   bool
 
+	// mu guards the container created by StartRelayer and its log stream,
+	// held by StartRelayer, StopRelayer, PauseRelayer and ResumeRelayer.
+	mu sync.Mutex
 	// The ID of the container created by StartRelayer.
 	containerLifecycle *dockerutil.ContainerLifecycle
 
+	// events collects the structured log lines of the relayer started by StartRelayer.
+	events *RelayerEvents
+	// logsDone is closed once the logs of the running relayer are no longer streamed.
+	logsDone chan struct{}
+
+	// execContainer is kept alive for the test to run commands through docker exec,
+	// started on the first call to Exec and removed by StopRelayer. execMu guards it.
+	execMu        sync.Mutex
+	execContainer *dockerutil.ContainerLifecycle
+	execFailed    bool
+
+	// chaos injects network faults into the relayer containers, created by NetworkChaos.
+	chaosMu sync.Mutex
+	chaos   *netchaos.Network
+
 	// wallets contains a mapping of chainID to relayer wallet
 	wallets map[string]ibc.Wallet
 
@@ -52,7 +70,7 @@ type DockerRelayer struct {
 var _ ibc.Relayer = (*DockerRelayer)(nil)
 
 // NewDockerRelayer returns a new DockerRelayer.
-func NewDockerRelayer(ctx context.Context, log *zap.Logger, testName string, cli *client.Client, relayerName, networkID string, c RelayerCommander, options ...RelayerOption) (*DockerRelayer, error) {
+func NewDockerRelayer(ctx context.Context, log *zap.Logger, testName string, cli dockerutil.Runtime, relayerName, networkID string, c RelayerCommander, options ...RelayerOption) (*DockerRelayer, error) {
 	r := DockerRelayer{
 		log: log,
 
@@ -68,6 +86,8 @@ func NewDockerRelayer(ctx context.Context, log *zap.Logger, testName string, cli
 		testName: testName,
 
 		wallets: map[string]ibc.Wallet{},
+
+		events: NewRelayerEvents(),
 	}
 
 	r.homeDir = defaultRlyHomeDirectory
@@ -83,7 +103,7 @@ func NewDockerRelayer(ctx context.Context, log *zap.Logger, testName string, cli
 		}
 	}
 
-	containerImage := r.containerImage()
+	containerImage := r.ContainerImage()
 	if err := r.pullContainerImageIfNecessary(containerImage); err != nil {
 		return nil, fmt.Errorf("pulling container image %s: %w", containerImage.Ref(), err)
 	}
@@ -155,7 +175,7 @@ func (r *DockerRelayer) AddChainConfiguration(ctx context.Context, rep ibc.Relay
 	}
 
 	fw := dockerutil.NewFileWriter(r.log, r.client, r.testName)
-	if err := fw.RelayerWriteFile(ctx, r.volumeName, r.relayerName, chainConfigFile, configContent); err != nil {
+	if err := fw.RelayerWriteFile(ctx, r.volumeName, r.hostHomeName(), chainConfigFile, configContent); err != nil {
 		return fmt.Errorf("failed to rly config: %w", err)
 	}
 
@@ -202,12 +222,39 @@ func (r *DockerRelayer) CreateChannel(ctx context.Context, rep ibc.RelayerExecRe
 	return res.Err
 }
 
+func (r *DockerRelayer) LinkConnection(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, clientOpts ibc.CreateClientOptions) error {
+	cmd := r.c.LinkConnection(pathName, clientOpts, r.HomeDir())
+	res := r.Exec(ctx, rep, cmd, nil)
+	return res.Err
+}
+
+func (r *DockerRelayer) CloseChannel(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID, portID string) error {
+	cmd := r.c.CloseChannel(pathName, channelID, portID, r.HomeDir())
+	res := r.Exec(ctx, rep, cmd, nil)
+	return res.Err
+}
+
+func (r *DockerRelayer) UpgradeChannel(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID, portID string, opts ibc.ChannelUpgradeOptions) error {
+	cmd := r.c.UpgradeChannel(pathName, channelID, portID, opts, r.HomeDir())
+	if len(cmd) == 0 {
+		return fmt.Errorf("relayer %s: %w", r.c.Name(), ibc.ErrChannelUpgradeUnsupported)
+	}
+	res := r.Exec(ctx, rep, cmd, nil)
+	return res.Err
+}
+
 func (r *DockerRelayer) CreateClients(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, opts ibc.CreateClientOptions) error {
 	cmd := r.c.CreateClients(pathName, opts, r.HomeDir())
 	res := r.Exec(ctx, rep, cmd, nil)
 	return res.Err
 }
 
+func (r *DockerRelayer) CreateClient(ctx context.Context, rep ibc.RelayerExecReporter, srcChainID, dstChainID, pathName string, opts ibc.CreateClientOptions) error {
+	cmd := r.c.CreateClient(srcChainID, dstChainID, pathName, opts, r.HomeDir())
+	res := r.Exec(ctx, rep, cmd, nil)
+	return res.Err
+}
+
 func (r *DockerRelayer) CreateConnections(ctx context.Context, rep ibc.RelayerExecReporter, pathName string) error {
 	cmd := r.c.CreateConnections(pathName, r.HomeDir())
 	res := r.Exec(ctx, rep, cmd, nil)
@@ -226,6 +273,18 @@ func (r *DockerRelayer) Flush(ctx context.Context, rep ibc.RelayerExecReporter,
 	return res.Err
 }
 
+func (r *DockerRelayer) RelayPackets(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID string, sequences ...uint64) error {
+	cmd := r.c.RelayPackets(pathName, channelID, sequences, r.HomeDir())
+	res := r.Exec(ctx, rep, cmd, nil)
+	return res.Err
+}
+
+func (r *DockerRelayer) RelayAcknowledgements(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID string) error {
+	cmd := r.c.RelayAcknowledgements(pathName, channelID, r.HomeDir())
+	res := r.Exec(ctx, rep, cmd, nil)
+	return res.Err
+}
+
 func (r *DockerRelayer) GeneratePath(ctx context.Context, rep ibc.RelayerExecReporter, srcChainID, dstChainID, pathName string) error {
 	cmd := r.c.GeneratePath(srcChainID, dstChainID, pathName, r.HomeDir())
 	res := r.Exec(ctx, rep, cmd, nil)
@@ -238,6 +297,12 @@ func (r *DockerRelayer) UpdatePath(ctx context.Context, rep ibc.RelayerExecRepor
 	return res.Err
 }
 
+func (r *DockerRelayer) SetPathIDs(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, ids ibc.PathIDs) error {
+	cmd := r.c.SetPathIDs(pathName, r.HomeDir(), ids)
+	res := r.Exec(ctx, rep, cmd, nil)
+	return res.Err
+}
+
 func (r *DockerRelayer) GetChannels(ctx context.Context, rep ibc.RelayerExecReporter, chainID string) ([]ibc.ChannelOutput, error) {
 	cmd := r.c.GetChannels(chainID, r.HomeDir())
 
@@ -279,15 +344,28 @@ func (r *DockerRelayer) LinkPath(ctx context.Context, rep ibc.RelayerExecReporte
 	return res.Err
 }
 
+// Exec runs cmd in the long-lived exec container of the relayer.
+// While the relayer is running, or if the exec container cannot be started,
+// cmd is run in a one-off container instead.
 func (r *DockerRelayer) Exec(ctx context.Context, rep ibc.RelayerExecReporter, cmd []string, env []string) ibc.RelayerExecResult {
-	job := dockerutil.NewImage(r.log, r.client, r.networkID, r.testName, r.containerImage().Repository, r.containerImage().Version)
-	opts := dockerutil.ContainerOptions{
-		Env:   env,
-		Binds: r.Bind(),
+	startedAt := time.Now()
+
+	var ec *dockerutil.ContainerLifecycle
+	if !r.running() {
+		ec = r.ensureExecContainer(ctx)
 	}
 
-	startedAt := time.Now()
-	res := job.Run(ctx, cmd, opts)
+	var res dockerutil.ContainerExecResult
+	if ec != nil {
+		res = ec.Exec(ctx, cmd, env)
+	} else {
+		job := dockerutil.NewImage(r.log, r.client, r.networkID, r.testName, r.ContainerImage().Repository, r.ContainerImage().Version)
+		opts := dockerutil.ContainerOptions{
+			Env:   env,
+			Binds: r.Bind(),
+		}
+		res = job.Run(ctx, cmd, opts)
+	}
 
 	defer func() {
 		rep.TrackRelayerExec(
@@ -308,6 +386,60 @@ func (r *DockerRelayer) Exec(ctx context.Context, rep ibc.RelayerExecReporter, c
 	}
 }
 
+// running reports whether the relayer was started by StartRelayer and not stopped since.
+func (r *DockerRelayer) running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.containerLifecycle != nil
+}
+
+// ensureExecContainer starts the exec container if it is not running yet.
+// It returns nil if the container could not be started.
+func (r *DockerRelayer) ensureExecContainer(ctx context.Context) *dockerutil.ContainerLifecycle {
+	r.execMu.Lock()
+	defer r.execMu.Unlock()
+
+	if r.execContainer != nil || r.execFailed {
+		return r.execContainer
+	}
+
+	containerName := fmt.Sprintf("%s-exec-%s", r.Name(), dockerutil.RandLowerCaseLetterString(5))
+	ec := dockerutil.NewContainerLifecycle(r.log, r.client, containerName)
+	if err := ec.CreateContainer(
+		ctx, r.testName, r.networkID, r.ContainerImage(), nil,
+		r.Bind(), r.HostName("exec"), []string{"sleep", "infinity"}, dockerutil.ContainerResources{},
+	); err != nil {
+		r.log.Info("Failed to create relayer exec container, using one-off containers", zap.Error(err))
+		r.execFailed = true
+		return nil
+	}
+	if err := ec.StartContainer(ctx); err != nil {
+		r.log.Info("Failed to start relayer exec container, using one-off containers", zap.Error(err))
+		_ = ec.RemoveContainer(ctx)
+		r.execFailed = true
+		return nil
+	}
+
+	r.execContainer = ec
+	return ec
+}
+
+// removeExecContainer removes the exec container, if any. The next command starts a new one.
+func (r *DockerRelayer) removeExecContainer(ctx context.Context) error {
+	r.execMu.Lock()
+	defer r.execMu.Unlock()
+
+	if r.execContainer == nil {
+		return nil
+	}
+	// The container only sleeps, so it is removed without waiting for it to stop.
+	if err := r.execContainer.RemoveContainer(ctx); err != nil {
+		return fmt.Errorf("failed to remove relayer exec container: %w", err)
+	}
+	r.execContainer = nil
+	return nil
+}
+
 func (r *DockerRelayer) RestoreKey(ctx context.Context, rep ibc.RelayerExecReporter, cfg ibc.ChainConfig, keyName, mnemonic string) error {
 	chainID := cfg.ChainID
 	coinType := cfg.CoinType
@@ -336,11 +468,14 @@ func (r *DockerRelayer) UpdateClients(ctx context.Context, rep ibc.RelayerExecRe
 }
 
 func (r *DockerRelayer) StartRelayer(ctx context.Context, rep ibc.RelayerExecReporter, pathNames ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if r.containerLifecycle != nil {
 		return fmt.Errorf("tried to start relayer again without stopping first")
 	}
 
-	containerImage := r.containerImage()
+	containerImage := r.ContainerImage()
 	joinedPaths := strings.Join(pathNames, ".")
 	containerName := fmt.Sprintf("%s-%s-%s", r.c.Name(), joinedPaths, dockerutil.RandLowerCaseLetterString(5))
 
@@ -350,15 +485,88 @@ func (r *DockerRelayer) StartRelayer(ctx context.Context, rep ibc.RelayerExecRep
 
 	if err := r.containerLifecycle.CreateContainer(
 		ctx, r.testName, r.networkID, containerImage, nil,
-		r.Bind(), r.HostName(joinedPaths), cmd,
+		r.Bind(), r.HostName(joinedPaths), cmd, dockerutil.ContainerResources{},
 	); err != nil {
 		return err
 	}
 
-	return r.containerLifecycle.StartContainer(ctx)
+	if err := r.containerLifecycle.StartContainer(ctx); err != nil {
+		return err
+	}
+
+	r.logsDone = make(chan struct{})
+	go r.streamLogs(containerName, r.containerLifecycle.ContainerID(), rep, r.logsDone)
+
+	return nil
+}
+
+// Events returns the structured log lines of the relayer, collected while it is running.
+// Events are kept across restarts of the relayer.
+func (r *DockerRelayer) Events() *RelayerEvents {
+	return r.events
+}
+
+// streamLogs follows the logs of the running relayer container until it stops,
+// logging every line, reporting it to rep if supported and collecting it as an event.
+// It closes done when the stream ends.
+func (r *DockerRelayer) streamLogs(containerName, containerID string, rep ibc.RelayerExecReporter, done chan struct{}) {
+	defer close(done)
+
+	// The stream ends once the container stops, it must outlive the ctx passed to StartRelayer.
+	rc, err := r.client.ContainerLogs(context.Background(), containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		r.log.Info("Failed to stream relayer logs", zap.String("container", containerName), zap.Error(err))
+		return
+	}
+	defer func() { _ = rc.Close() }()
+
+	log := r.log.With(zap.String("container", containerName))
+	stdout := relayerLogWriter(log, r.events, rep, containerName, "stdout")
+	stderr := relayerLogWriter(log, r.events, rep, containerName, "stderr")
+
+	// Logs are multiplexed into one stream; see docs for ContainerLogs.
+	if _, err := stdcopy.StdCopy(stdout, stderr, rc); err != nil {
+		r.log.Info("Relayer log stream ended", zap.String("container", containerName), zap.Error(err))
+	}
+	stdout.Flush()
+	stderr.Flush()
+}
+
+// PauseRelayer pauses the container of the running relayer.
+func (r *DockerRelayer) PauseRelayer(ctx context.Context, rep ibc.RelayerExecReporter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.containerLifecycle == nil {
+		return fmt.Errorf("tried to pause relayer that is not running")
+	}
+	return r.containerLifecycle.PauseContainer(ctx)
 }
 
+// ResumeRelayer unpauses the container of the running relayer.
+func (r *DockerRelayer) ResumeRelayer(ctx context.Context, rep ibc.RelayerExecReporter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.containerLifecycle == nil {
+		return fmt.Errorf("tried to resume relayer that is not running")
+	}
+	return r.containerLifecycle.UnpauseContainer(ctx)
+}
+
+// StopRelayer stops and removes the container of the running relayer, and the exec container.
 func (r *DockerRelayer) StopRelayer(ctx context.Context, rep ibc.RelayerExecReporter) error {
+	if err := r.removeExecContainer(ctx); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if r.containerLifecycle == nil {
 		return nil
 	}
@@ -366,6 +574,13 @@ func (r *DockerRelayer) StopRelayer(ctx context.Context, rep ibc.RelayerExecRepo
 		return err
 	}
 
+	// Let the log stream catch up with the last lines of the stopped container.
+	select {
+	case <-r.logsDone:
+	case <-time.After(10 * time.Second):
+		r.log.Info("Timed out waiting for relayer log stream to end")
+	}
+
 	stdoutBuf := new(bytes.Buffer)
 	stderrBuf := new(bytes.Buffer)
 	containerID := r.containerLifecycle.ContainerID()
@@ -431,7 +646,8 @@ func (r *DockerRelayer) StopRelayer(ctx context.Context, rep ibc.RelayerExecRepo
 	return nil
 }
 
-func (r *DockerRelayer) containerImage() ibc.DockerImage {
+// ContainerImage returns the image the relayer runs, checked by the image preflight of Setup.Build.
+func (r *DockerRelayer) ContainerImage() ibc.DockerImage {
 	if r.customImage != nil {
 		return *r.customImage
 	}
@@ -447,14 +663,7 @@ func (r *DockerRelayer) pullContainerImageIfNecessary(containerImage ibc.DockerI
 		return nil
 	}
 
-	rc, err := r.client.ImagePull(context.TODO(), containerImage.Ref(), types.ImagePullOptions{})
-	if err != nil {
-		return err
-	}
-
-	_, _ = io.Copy(io.Discard, rc)
-	_ = rc.Close()
-	return nil
+	return containerImage.Ensure(context.TODO(), r.log, r.client)
 }
 
 func (r *DockerRelayer) Name() string {
@@ -463,7 +672,17 @@ func (r *DockerRelayer) Name() string {
 
 // Bind returns the home folder bind point for running the node.
 func (r *DockerRelayer) Bind() []string {
-	return []string{"/tmp/" + r.relayerName + ":" + r.HomeDir()}
+	return []string{r.HostHomeDir() + ":" + r.HomeDir()}
+}
+
+// HostHomeDir returns the directory of the host bound to the home directory of the relayer, see Bind.
+// It is scoped to the test, so relayers of the same name in different tests do not share their home.
+func (r *DockerRelayer) HostHomeDir() string {
+	return "/tmp/" + r.hostHomeName()
+}
+
+func (r *DockerRelayer) hostHomeName() string {
+	return r.relayerName + "-" + dockerutil.SanitizeContainerName(r.testName)
 }
 
 // HomeDir returns the home directory of the relayer on the underlying Docker container's filesystem.
@@ -525,12 +744,20 @@ type RelayerCommander interface {
 	AddChainConfiguration(containerFilePath, homeDir string) []string
 	AddKey(chainID, keyName, coinType, homeDir string) []string
 	CreateChannel(pathName string, opts ibc.CreateChannelOptions, homeDir string) []string
+	LinkConnection(pathName string, clientOpts ibc.CreateClientOptions, homeDir string) []string
+	CloseChannel(pathName, channelID, portID, homeDir string) []string
+	// UpgradeChannel returns nil if the relayer does not support channel upgrades.
+	UpgradeChannel(pathName, channelID, portID string, opts ibc.ChannelUpgradeOptions, homeDir string) []string
 	CreateClients(pathName string, opts ibc.CreateClientOptions, homeDir string) []string
+	CreateClient(srcChainID, dstChainID, pathName string, opts ibc.CreateClientOptions, homeDir string) []string
 	CreateConnections(pathName, homeDir string) []string
 	CreateConnectionsWithNumberOfRetries(pathName, homeDir, retries string) []string
 	Flush(pathName, channelID, homeDir string) []string
+	RelayPackets(pathName, channelID string, sequences []uint64, homeDir string) []string
+	RelayAcknowledgements(pathName, channelID, homeDir string) []string
 	GeneratePath(srcChainID, dstChainID, pathName, homeDir string) []string
 	UpdatePath(pathName, homeDir string, filter ibc.ChannelFilter) []string
+	SetPathIDs(pathName, homeDir string, ids ibc.PathIDs) []string
 	GetChannels(chainID, homeDir string) []string
 	GetConnections(chainID, homeDir string) []string
 	GetClients(chainID, homeDir string) []string
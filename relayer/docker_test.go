@@ -0,0 +1,129 @@
+package relayer_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/relayer"
+	rly "github.com/decentrio/rollup-e2e-testing/relayer/rly"
+)
+
+func TestDockerRelayerStartRelayerAndExec(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const logLine = `{"level":"info","ts":"2024-01-02T03:04:05Z","msg":"Successful transaction","chain_id":"rollappevm_1234-1",` +
+		`"packet_src_channel":"channel-0","packet_dst_channel":"channel-1","packet_sequence":1,` +
+		`"msg_types":["/ibc.core.channel.v1.MsgRecvPacket"]}` + "\n"
+
+	type execCall struct {
+		container string
+		cmd       string
+	}
+	var (
+		mu    sync.Mutex
+		execs []execCall
+	)
+	rt := dockerutil.NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		switch {
+		case cmd[0] == "sleep":
+			return dockerutil.FakeResult{Running: true}
+		case len(cmd) > 1 && cmd[0] == "rly" && cmd[1] == "start":
+			return dockerutil.FakeResult{Stdout: []byte(logLine), Running: true}
+		case cmd[0] == "rly":
+			mu.Lock()
+			defer mu.Unlock()
+			execs = append(execs, execCall{containerName, strings.Join(cmd, " ")})
+			return dockerutil.FakeResult{Stdout: []byte("ok")}
+		}
+		return dockerutil.FakeResult{}
+	}
+
+	r := rly.NewCosmosRelayer(zaptest.NewLogger(t), t.Name(), rt, "rly", "net", relayer.ImagePull(false))
+	rep := ibc.NopRelayerExecReporter{}
+
+	// Commands run in the long-lived exec container while the relayer is stopped.
+	res := r.Exec(ctx, rep, []string{"rly", "version"}, nil)
+	require.NoError(t, res.Err)
+	require.Equal(t, "ok", string(res.Stdout))
+	require.Len(t, execs, 2)
+	require.Equal(t, "rly config init --home "+r.HomeDir(), execs[0].cmd)
+	require.Contains(t, execs[0].container, "-exec-")
+	require.Equal(t, execs[0].container, execs[1].container)
+
+	// Packets of chosen sequences are relayed without their acknowledgements.
+	require.NoError(t, r.RelayPackets(ctx, rep, "hub-ra", "channel-0", 1, 3))
+	require.NoError(t, r.RelayAcknowledgements(ctx, rep, "hub-ra", "channel-0"))
+	require.Equal(t, "rly tx relay-packets hub-ra channel-0 --home "+r.HomeDir()+" --seqs 1,3", execs[2].cmd)
+	require.Equal(t, "rly tx relay-acknowledgements hub-ra channel-0 --home "+r.HomeDir(), execs[3].cmd)
+	execs = execs[:2]
+
+	// Channel upgrades are reported as unsupported without running any command.
+	upgrade := ibc.ChannelUpgradeOptions{Version: "ics20-2"}
+	require.ErrorIs(t, r.UpgradeChannel(ctx, rep, "hub-ra", "channel-0", "transfer", upgrade), ibc.ErrChannelUpgradeUnsupported)
+	require.Len(t, execs, 2)
+
+	require.NoError(t, r.StartRelayer(ctx, rep, "hub-ra"))
+	require.Error(t, r.StartRelayer(ctx, rep, "hub-ra"))
+
+	containers, err := rt.ContainerList(ctx, types.ContainerListOptions{})
+	require.NoError(t, err)
+	var startCmd []string
+	for _, c := range containers {
+		if strings.HasPrefix(c.Names[0], "/rly-hub-ra-") {
+			cont, err := rt.ContainerInspect(ctx, c.ID)
+			require.NoError(t, err)
+			startCmd = cont.Config.Cmd
+		}
+	}
+	require.Equal(t, "rly start --debug --home "+r.HomeDir()+" --log-format json hub-ra", strings.Join(startCmd, " "))
+
+	// The log lines of the running relayer are collected as events.
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	require.NoError(t, r.Events().WaitForRelayedPacket(waitCtx, "channel-0", 1))
+
+	// Commands run in one-off containers while the relayer is running.
+	res = r.Exec(ctx, rep, []string{"rly", "paths", "list"}, nil)
+	require.NoError(t, res.Err)
+	require.Len(t, execs, 3)
+	require.NotEqual(t, execs[0].container, execs[2].container)
+
+	require.NoError(t, r.StopRelayer(ctx, rep))
+	containers, err = rt.ContainerList(ctx, types.ContainerListOptions{All: true})
+	require.NoError(t, err)
+	for _, c := range containers {
+		require.False(t, strings.HasPrefix(c.Names[0], "/rly-hub-ra-"), "relayer container %s not removed", c.Names[0])
+		require.NotContains(t, c.Names[0], "-exec-", "exec container %s not removed", c.Names[0])
+	}
+
+	// The relayer can start again once stopped.
+	require.NoError(t, r.StartRelayer(ctx, rep, "hub-ra"))
+	require.NoError(t, r.StopRelayer(ctx, rep))
+}
+
+func TestDockerRelayerHostHomeDir(t *testing.T) {
+	t.Parallel()
+
+	rt := dockerutil.NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		return dockerutil.FakeResult{Running: cmd[0] == "sleep"}
+	}
+	r1 := rly.NewCosmosRelayer(zaptest.NewLogger(t), t.Name()+"-1", rt, "rly", "net", relayer.ImagePull(false))
+	r2 := rly.NewCosmosRelayer(zaptest.NewLogger(t), t.Name()+"-2", rt, "rly", "net", relayer.ImagePull(false))
+
+	// Relayers of the same name in different tests do not share their home.
+	require.NotEqual(t, r1.HostHomeDir(), r2.HostHomeDir())
+	require.Equal(t, []string{r1.HostHomeDir() + ":" + r1.HomeDir()}, r1.Bind())
+}
@@ -0,0 +1,319 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"go.uber.org/zap"
+)
+
+// Message types of relayed packets, as logged by the relayer.
+const (
+	MsgRecvPacket      = "/ibc.core.channel.v1.MsgRecvPacket"
+	MsgAcknowledgement = "/ibc.core.channel.v1.MsgAcknowledgement"
+	MsgTimeout         = "/ibc.core.channel.v1.MsgTimeout"
+	MsgUpdateClient    = "/ibc.core.client.v1.MsgUpdateClient"
+)
+
+// RelayerEvent is a structured JSON log line of the relayer.
+type RelayerEvent struct {
+	Time    time.Time
+	Level   string
+	Msg     string
+	ChainID string
+
+	// SrcChainID and DstChainID are the chains of the path the line is about, if any.
+	SrcChainID string
+	DstChainID string
+
+	// MsgTypes are the message types of a transaction sent by the relayer.
+	MsgTypes []string
+	// Packets are the packet messages of a transaction sent by the relayer.
+	Packets []RelayedPacket
+	// ClientIDs are the clients updated by a transaction sent by the relayer, when logged.
+	ClientIDs []string
+	// Error is the error logged with the line, if any, and ErrorVerbose its details, e.g. a stack trace.
+	Error        string
+	ErrorVerbose string
+
+	// Fields are all the fields of the line.
+	Fields map[string]any
+}
+
+// IsError reports whether the line was logged at error level.
+func (e RelayerEvent) IsError() bool {
+	return e.Level == "error"
+}
+
+// HasMsgType reports whether the transaction of the line has a message of msgType.
+func (e RelayerEvent) HasMsgType(msgType string) bool {
+	for _, t := range e.MsgTypes {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// RelayedPacket is a packet message in a transaction sent by the relayer.
+type RelayedPacket struct {
+	MsgType    string
+	SrcPort    string
+	SrcChannel string
+	DstPort    string
+	DstChannel string
+	Sequence   uint64
+}
+
+// ParseRlyLogLine parses a JSON log line of the cosmos relayer, logged with --log-format json.
+// It returns false if the line is not a JSON object, e.g. console output.
+//
+// The relayer logs flat fields. For instance, a transaction relaying a packet is logged as
+//
+//	{"level":"info","ts":"2024-01-02T03:04:05.000000006Z","msg":"Successful transaction","provider_type":"cosmos",
+//	 "chain_id":"rollappevm_1234-1","packet_src_channel":"channel-0","packet_dst_channel":"channel-1",
+//	 "gas_used":103246,"height":42,"msg_types":["/ibc.core.client.v1.MsgUpdateClient","/ibc.core.channel.v1.MsgRecvPacket"],
+//	 "tx_hash":"8E2B...","packet_sequence":3}
+//
+// and a failed one with "error" and "errorVerbose" fields, and the "src_chain_id" and "dst_chain_id" of the path.
+func ParseRlyLogLine(line string) (RelayerEvent, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &fields); err != nil {
+		return RelayerEvent{}, false
+	}
+
+	e := RelayerEvent{
+		Level:        stringField(fields, "level"),
+		Msg:          stringField(fields, "msg"),
+		ChainID:      stringField(fields, "chain_id"),
+		SrcChainID:   stringField(fields, "src_chain_id"),
+		DstChainID:   stringField(fields, "dst_chain_id"),
+		Error:        stringField(fields, "error"),
+		ErrorVerbose: stringField(fields, "errorVerbose", "errorsVerbose"),
+		Fields:       fields,
+	}
+
+	switch ts := fields["ts"].(type) {
+	case string:
+		e.Time, _ = time.Parse(time.RFC3339Nano, ts)
+	case float64:
+		e.Time = time.Unix(0, int64(ts*float64(time.Second)))
+	}
+
+	if types, ok := fields["msg_types"].([]any); ok {
+		for _, t := range types {
+			if t, ok := t.(string); ok {
+				e.MsgTypes = append(e.MsgTypes, t)
+			}
+		}
+	}
+
+	// The packet fields are only logged for transactions carrying a single packet.
+	seq, seqErr := strconv.ParseUint(fmt.Sprint(firstField(fields, "packet_sequence", "sequence")), 10, 64)
+	if seqErr == nil {
+		for _, typ := range e.MsgTypes {
+			switch typ {
+			case MsgRecvPacket, MsgAcknowledgement, MsgTimeout:
+				e.Packets = append(e.Packets, RelayedPacket{
+					MsgType:    typ,
+					SrcPort:    stringField(fields, "packet_src_port", "src_port"),
+					SrcChannel: stringField(fields, "packet_src_channel", "src_channel"),
+					DstPort:    stringField(fields, "packet_dst_port", "dst_port"),
+					DstChannel: stringField(fields, "packet_dst_channel", "dst_channel"),
+					Sequence:   seq,
+				})
+			}
+		}
+	}
+
+	if e.HasMsgType(MsgUpdateClient) {
+		if id := stringField(fields, "client_id"); id != "" {
+			e.ClientIDs = append(e.ClientIDs, id)
+		}
+	}
+
+	return e, true
+}
+
+// stringField returns the first of keys that is a string field of fields.
+func stringField(fields map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := fields[key].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// firstField returns the first of keys that is set in fields, or nil.
+func firstField(fields map[string]any, keys ...string) any {
+	for _, key := range keys {
+		if v, ok := fields[key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// RelayerEvents collects the events of a relayer so tests can wait on or assert against them.
+// It is safe for concurrent use.
+type RelayerEvents struct {
+	mu     sync.Mutex
+	events []RelayerEvent
+	// notify is closed and replaced whenever an event is added.
+	notify chan struct{}
+}
+
+// NewRelayerEvents returns an empty RelayerEvents.
+func NewRelayerEvents() *RelayerEvents {
+	return &RelayerEvents{notify: make(chan struct{})}
+}
+
+// Add records e and wakes up any waiters.
+func (r *RelayerEvents) Add(e RelayerEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+// All returns a copy of the events collected so far.
+func (r *RelayerEvents) All() []RelayerEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RelayerEvent(nil), r.events...)
+}
+
+// Errors returns the events logged at error level.
+func (r *RelayerEvents) Errors() []RelayerEvent {
+	var errs []RelayerEvent
+	for _, e := range r.All() {
+		if e.IsError() {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
+
+// WaitFor blocks until an event matching match has been collected, including events collected before the call.
+func (r *RelayerEvents) WaitFor(ctx context.Context, match func(RelayerEvent) bool) (RelayerEvent, error) {
+	next := 0
+	for {
+		r.mu.Lock()
+		events, notify := r.events[next:], r.notify
+		next = len(r.events)
+		r.mu.Unlock()
+
+		for _, e := range events {
+			if match(e) {
+				return e, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return RelayerEvent{}, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+// WaitForPacket blocks until the relayer sent a msgType message, e.g. MsgRecvPacket,
+// for the packet with sequence seq sent on srcChannel.
+func (r *RelayerEvents) WaitForPacket(ctx context.Context, msgType, srcChannel string, seq uint64) (RelayedPacket, error) {
+	var found RelayedPacket
+	_, err := r.WaitFor(ctx, func(e RelayerEvent) bool {
+		for _, p := range e.Packets {
+			if p.MsgType == msgType && p.SrcChannel == srcChannel && p.Sequence == seq {
+				found = p
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return RelayedPacket{}, fmt.Errorf("failed to wait for %s of packet %d on %s: %w", msgType, seq, srcChannel, err)
+	}
+	return found, nil
+}
+
+// WaitForRelayedPacket blocks until the relayer delivered the packet with sequence seq sent on srcChannel.
+func (r *RelayerEvents) WaitForRelayedPacket(ctx context.Context, srcChannel string, seq uint64) error {
+	_, err := r.WaitForPacket(ctx, MsgRecvPacket, srcChannel, seq)
+	return err
+}
+
+// WaitForClientUpdate blocks until the relayer updated clientID on chainID.
+// The relayer does not always log the updated client, an empty clientID matches any client update on chainID.
+func (r *RelayerEvents) WaitForClientUpdate(ctx context.Context, chainID, clientID string) error {
+	_, err := r.WaitFor(ctx, func(e RelayerEvent) bool {
+		if e.ChainID != chainID || !e.HasMsgType(MsgUpdateClient) {
+			return false
+		}
+		if clientID == "" {
+			return true
+		}
+		for _, id := range e.ClientIDs {
+			if id == clientID {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for update of client %s on %s: %w", clientID, chainID, err)
+	}
+	return nil
+}
+
+// relayerLogWriter returns a writer handling every line logged on stream by the relayer named name:
+// the line is logged to log, reported to rep if it is an ibc.RelayerLogReporter,
+// and collected into events if it is a JSON line.
+func relayerLogWriter(log *zap.Logger, events *RelayerEvents, rep ibc.RelayerExecReporter, name, stream string) *lineWriter {
+	logReporter, _ := rep.(ibc.RelayerLogReporter)
+	return &lineWriter{fn: func(line string) {
+		log.Debug(line, zap.String("stream", stream))
+		if logReporter != nil {
+			logReporter.TrackRelayerLog(name, stream, line, time.Now())
+		}
+		if e, ok := ParseRlyLogLine(line); ok {
+			events.Add(e)
+		}
+	}}
+}
+
+// lineWriter calls fn with every complete line written to it.
+type lineWriter struct {
+	buf []byte
+	fn  func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.fn(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush calls fn with any incomplete line left in the buffer.
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.fn(string(w.buf))
+		w.buf = nil
+	}
+}
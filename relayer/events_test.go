@@ -0,0 +1,78 @@
+package relayer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRlyLogLine(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successful transaction", func(t *testing.T) {
+		const line = `{"level":"info","ts":"2024-01-02T03:04:05.000000006Z","msg":"Successful transaction","provider_type":"cosmos",` +
+			`"chain_id":"rollappevm_1234-1","packet_src_channel":"channel-0","packet_dst_channel":"channel-1","gas_used":103246,` +
+			`"fees":"2677adym","fee_payer":"dym1q9rj7vnz2e8jkgzqvxj9g7q6h5dr8r0p9hq3f5","height":42,` +
+			`"msg_types":["/ibc.core.client.v1.MsgUpdateClient","/ibc.core.channel.v1.MsgRecvPacket"],` +
+			`"tx_hash":"8E2B0A5C7D4F1E3B9A6C2D8F0E4A7B1C3D5E9F2A6B8C0D4E7F1A3B5C9D2E6F0A","packet_sequence":3}`
+
+		e, ok := ParseRlyLogLine(line)
+		require.True(t, ok)
+		require.Equal(t, "Successful transaction", e.Msg)
+		require.Equal(t, "rollappevm_1234-1", e.ChainID)
+		require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC), e.Time)
+		require.True(t, e.HasMsgType(MsgUpdateClient))
+		require.Empty(t, e.ClientIDs)
+		require.Equal(t, []RelayedPacket{{
+			MsgType:    MsgRecvPacket,
+			SrcChannel: "channel-0",
+			DstChannel: "channel-1",
+			Sequence:   3,
+		}}, e.Packets)
+		require.False(t, e.IsError())
+	})
+
+	t.Run("failed transaction", func(t *testing.T) {
+		const line = `{"level":"error","ts":1704164645.5,"msg":"Error sending messages","provider_type":"cosmos","chain_id":"dymension_100-1",` +
+			`"path_name":"hub-rollapp","src_chain_id":"rollappevm_1234-1","dst_chain_id":"dymension_100-1",` +
+			`"src_client_id":"07-tendermint-0","dst_client_id":"07-tendermint-1",` +
+			`"error":"account sequence mismatch, expected 12, got 11: incorrect account sequence",` +
+			`"errorVerbose":"account sequence mismatch, expected 12, got 11: incorrect account sequence\ngithub.com/cosmos/relayer/v2/relayer/chains/cosmos.(*CosmosProvider).SendMessages"}`
+
+		e, ok := ParseRlyLogLine(line)
+		require.True(t, ok)
+		require.True(t, e.IsError())
+		require.Equal(t, "rollappevm_1234-1", e.SrcChainID)
+		require.Equal(t, "dymension_100-1", e.DstChainID)
+		require.Equal(t, time.Unix(1704164645, 500000000), e.Time)
+		require.Contains(t, e.Error, "account sequence mismatch")
+		require.Contains(t, e.ErrorVerbose, "SendMessages")
+		require.Empty(t, e.Packets)
+	})
+
+	t.Run("console output", func(t *testing.T) {
+		_, ok := ParseRlyLogLine("2024-01-02T03:04:05Z	info	Chain is in sync")
+		require.False(t, ok)
+	})
+}
+
+func TestRelayerEventsWaitForRelayedPacket(t *testing.T) {
+	t.Parallel()
+
+	events := NewRelayerEvents()
+	events.Add(RelayerEvent{Packets: []RelayedPacket{{MsgType: MsgRecvPacket, SrcChannel: "channel-0", Sequence: 1}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, events.WaitForRelayedPacket(ctx, "channel-0", 1))
+
+	go events.Add(RelayerEvent{Packets: []RelayedPacket{{MsgType: MsgRecvPacket, SrcChannel: "channel-0", Sequence: 2}}})
+	require.NoError(t, events.WaitForRelayedPacket(ctx, "channel-0", 2))
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.Error(t, events.WaitForRelayedPacket(short, "channel-1", 1))
+}
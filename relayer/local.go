@@ -0,0 +1,537 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"go.uber.org/zap"
+)
+
+// LocalRelayer provides a common base for relayer implementations
+// that run as processes on the host instead of in Docker.
+//
+// Every command runs the relayer binary directly against a home directory on the host,
+// which avoids creating a container per command and makes path creation and flushes much faster.
+// The chains are reached through their host-exposed ports.
+//
+// LocalRelayer is not an in-process relayer: every command is still a separate process,
+// and the relayer binary must be installed on the host, see LocalBinary.
+type LocalRelayer struct {
+	log         *zap.Logger
+	relayerName string
+	// c defines all the commands to run, the first element of each command is replaced by binary.
+	c RelayerCommander
+
+	testName string
+	binary   string
+	homeDir  string
+
+	// mu guards the fields below, set by StartRelayer.
+	mu        sync.Mutex
+	running   *exec.Cmd
+	runCmd    []string
+	runPaths  []string
+	startedAt time.Time
+	stdout    *bytes.Buffer
+	stderr    *bytes.Buffer
+	logs      []*lineWriter
+	done      chan error
+
+	// events collects the structured log lines of the relayer started by StartRelayer.
+	events *RelayerEvents
+
+	// wallets contains a mapping of chainID to relayer wallet
+	wallets map[string]ibc.Wallet
+}
+
+var (
+	_ ibc.Relayer            = (*LocalRelayer)(nil)
+	_ ibc.HostAddressUpdater = (*LocalRelayer)(nil)
+)
+
+// NewLocalRelayer returns a new LocalRelayer.
+// The relayer binary defaults to the commander name looked up in PATH,
+// and can be overridden with the LocalBinary option.
+func NewLocalRelayer(ctx context.Context, log *zap.Logger, testName, relayerName string, c RelayerCommander, options ...RelayerOption) (*LocalRelayer, error) {
+	r := LocalRelayer{
+		log: log,
+
+		c: c,
+
+		relayerName: relayerName,
+		testName:    testName,
+		binary:      c.Name(),
+
+		wallets: map[string]ibc.Wallet{},
+		events:  NewRelayerEvents(),
+	}
+
+	for _, opt := range options {
+		switch o := opt.(type) {
+		case RelayerOptionLocalBinary:
+			r.binary = o.Path
+		case RelayerOptionHomeDir:
+			r.homeDir = o.HomeDir
+		}
+	}
+
+	if _, err := exec.LookPath(r.binary); err != nil {
+		return nil, fmt.Errorf("failed to find relayer binary %s on the host, install it or set relayer.LocalBinary: %w", r.binary, err)
+	}
+
+	if r.homeDir == "" {
+		dir, err := os.MkdirTemp("", dockerutil.SanitizeContainerName(relayerName)+"-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create relayer home directory: %w", err)
+		}
+		r.homeDir = dir
+	} else if err := os.MkdirAll(r.homeDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create relayer home directory: %w", err)
+	}
+
+	if init := r.c.Init(r.HomeDir()); len(init) > 0 {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+
+		// Using a nop reporter here because it keeps the API simpler,
+		// and the init command is typically not of high interest.
+		res := r.Exec(ctx, ibc.NopRelayerExecReporter{}, init, nil)
+		if res.Err != nil {
+			return nil, res.Err
+		}
+	}
+
+	return &r, nil
+}
+
+// AddWallet adds a stores a wallet for the given chain ID.
+func (r *LocalRelayer) AddWallet(chainID string, wallet ibc.Wallet) {
+	r.wallets[chainID] = wallet
+}
+
+func (r *LocalRelayer) AddChainConfiguration(ctx context.Context, rep ibc.RelayerExecReporter, chainConfig ibc.ChainConfig, keyName, rpcAddr, grpcAddr, apiAddr string, trusting_period int64) error {
+	chainConfigFilePath := filepath.Join(r.HomeDir(), chainConfig.ChainID+".config")
+
+	configContent, err := r.c.ConfigContent(ctx, chainConfig, keyName, rpcAddr, grpcAddr, apiAddr, trusting_period)
+	if err != nil {
+		return fmt.Errorf("failed to generate config content: %w", err)
+	}
+
+	if err := os.WriteFile(chainConfigFilePath, configContent, 0o644); err != nil {
+		return fmt.Errorf("failed to write rly config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	res := r.Exec(ctx, rep, r.c.AddChainConfiguration(chainConfigFilePath, r.HomeDir()), nil)
+	return res.Err
+}
+
+func (r *LocalRelayer) AddKey(ctx context.Context, rep ibc.RelayerExecReporter, chainID, keyName, coinType string) (ibc.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	res := r.Exec(ctx, rep, r.c.AddKey(chainID, keyName, coinType, r.HomeDir()), nil)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	wallet, err := r.c.ParseAddKeyOutput(string(res.Stdout), string(res.Stderr))
+	if err != nil {
+		return nil, err
+	}
+	r.wallets[chainID] = wallet
+	return wallet, nil
+}
+
+func (r *LocalRelayer) RestoreKey(ctx context.Context, rep ibc.RelayerExecReporter, cfg ibc.ChainConfig, keyName, mnemonic string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	res := r.Exec(ctx, rep, r.c.RestoreKey(cfg.ChainID, keyName, cfg.CoinType, mnemonic, r.HomeDir()), nil)
+	if res.Err != nil {
+		return res.Err
+	}
+	addrBytes := r.c.ParseRestoreKeyOutput(string(res.Stdout), string(res.Stderr))
+
+	r.wallets[cfg.ChainID] = r.c.CreateWallet("", addrBytes, mnemonic)
+
+	return nil
+}
+
+func (r *LocalRelayer) GetWallet(chainID string) (ibc.Wallet, bool) {
+	wallet, ok := r.wallets[chainID]
+	return wallet, ok
+}
+
+func (r *LocalRelayer) CreateChannel(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, opts ibc.CreateChannelOptions) error {
+	return r.Exec(ctx, rep, r.c.CreateChannel(pathName, opts, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) LinkConnection(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, clientOpts ibc.CreateClientOptions) error {
+	return r.Exec(ctx, rep, r.c.LinkConnection(pathName, clientOpts, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) CloseChannel(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID, portID string) error {
+	return r.Exec(ctx, rep, r.c.CloseChannel(pathName, channelID, portID, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) UpgradeChannel(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID, portID string, opts ibc.ChannelUpgradeOptions) error {
+	cmd := r.c.UpgradeChannel(pathName, channelID, portID, opts, r.HomeDir())
+	if len(cmd) == 0 {
+		return fmt.Errorf("relayer %s: %w", r.c.Name(), ibc.ErrChannelUpgradeUnsupported)
+	}
+	return r.Exec(ctx, rep, cmd, nil).Err
+}
+
+func (r *LocalRelayer) CreateClients(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, opts ibc.CreateClientOptions) error {
+	return r.Exec(ctx, rep, r.c.CreateClients(pathName, opts, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) CreateClient(ctx context.Context, rep ibc.RelayerExecReporter, srcChainID, dstChainID, pathName string, opts ibc.CreateClientOptions) error {
+	return r.Exec(ctx, rep, r.c.CreateClient(srcChainID, dstChainID, pathName, opts, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) CreateConnections(ctx context.Context, rep ibc.RelayerExecReporter, pathName string) error {
+	return r.Exec(ctx, rep, r.c.CreateConnections(pathName, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) CreateConnectionsWithNumberOfRetries(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, retries string) error {
+	return r.Exec(ctx, rep, r.c.CreateConnectionsWithNumberOfRetries(pathName, r.HomeDir(), retries), nil).Err
+}
+
+func (r *LocalRelayer) Flush(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID string) error {
+	return r.Exec(ctx, rep, r.c.Flush(pathName, channelID, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) RelayPackets(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID string, sequences ...uint64) error {
+	return r.Exec(ctx, rep, r.c.RelayPackets(pathName, channelID, sequences, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) RelayAcknowledgements(ctx context.Context, rep ibc.RelayerExecReporter, pathName, channelID string) error {
+	return r.Exec(ctx, rep, r.c.RelayAcknowledgements(pathName, channelID, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) GeneratePath(ctx context.Context, rep ibc.RelayerExecReporter, srcChainID, dstChainID, pathName string) error {
+	return r.Exec(ctx, rep, r.c.GeneratePath(srcChainID, dstChainID, pathName, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) UpdatePath(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, filter ibc.ChannelFilter) error {
+	return r.Exec(ctx, rep, r.c.UpdatePath(pathName, r.HomeDir(), filter), nil).Err
+}
+
+func (r *LocalRelayer) SetPathIDs(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, ids ibc.PathIDs) error {
+	return r.Exec(ctx, rep, r.c.SetPathIDs(pathName, r.HomeDir(), ids), nil).Err
+}
+
+func (r *LocalRelayer) LinkPath(ctx context.Context, rep ibc.RelayerExecReporter, pathName string, channelOpts ibc.CreateChannelOptions, clientOpts ibc.CreateClientOptions) error {
+	return r.Exec(ctx, rep, r.c.LinkPath(pathName, r.HomeDir(), channelOpts, clientOpts), nil).Err
+}
+
+func (r *LocalRelayer) UpdateClients(ctx context.Context, rep ibc.RelayerExecReporter, pathName string) error {
+	return r.Exec(ctx, rep, r.c.UpdateClients(pathName, r.HomeDir()), nil).Err
+}
+
+func (r *LocalRelayer) GetChannels(ctx context.Context, rep ibc.RelayerExecReporter, chainID string) ([]ibc.ChannelOutput, error) {
+	res := r.Exec(ctx, rep, r.c.GetChannels(chainID, r.HomeDir()), nil)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	return r.c.ParseGetChannelsOutput(string(res.Stdout), string(res.Stderr))
+}
+
+func (r *LocalRelayer) GetConnections(ctx context.Context, rep ibc.RelayerExecReporter, chainID string) (ibc.ConnectionOutputs, error) {
+	res := r.Exec(ctx, rep, r.c.GetConnections(chainID, r.HomeDir()), nil)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	return r.c.ParseGetConnectionsOutput(string(res.Stdout), string(res.Stderr))
+}
+
+func (r *LocalRelayer) GetClients(ctx context.Context, rep ibc.RelayerExecReporter, chainID string) (ibc.ClientOutputs, error) {
+	res := r.Exec(ctx, rep, r.c.GetClients(chainID, r.HomeDir()), nil)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	return r.c.ParseGetClientsOutput(string(res.Stdout), string(res.Stderr))
+}
+
+// Exec runs cmd as a process on the host, with the relayer binary in place of the first element.
+func (r *LocalRelayer) Exec(ctx context.Context, rep ibc.RelayerExecReporter, cmd []string, env []string) ibc.RelayerExecResult {
+	startedAt := time.Now()
+
+	var stdout, stderr bytes.Buffer
+	proc := r.command(ctx, cmd, env)
+	proc.Stdout = &stdout
+	proc.Stderr = &stderr
+
+	err := proc.Run()
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+		out := strings.Join([]string{stdout.String(), stderr.String()}, " ")
+		err = fmt.Errorf("exit code %d: %s", exitCode, out)
+	} else if err != nil {
+		exitCode = -1
+	}
+
+	rep.TrackRelayerExec(
+		r.Name(),
+		cmd,
+		stdout.String(), stderr.String(),
+		exitCode,
+		startedAt, time.Now(),
+		err,
+	)
+
+	return ibc.RelayerExecResult{
+		Err:      err,
+		ExitCode: exitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+	}
+}
+
+func (r *LocalRelayer) StartRelayer(ctx context.Context, rep ibc.RelayerExecReporter, pathNames ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running != nil {
+		return fmt.Errorf("tried to start relayer again without stopping first")
+	}
+
+	cmd := r.c.StartRelayer(r.HomeDir(), pathNames...)
+
+	// The relayer outlives the ctx of this call, it is only stopped through StopRelayer.
+	proc := r.command(context.Background(), cmd, nil)
+	r.stdout, r.stderr = new(bytes.Buffer), new(bytes.Buffer)
+	stdoutLog := relayerLogWriter(r.log, r.events, rep, r.Name(), "stdout")
+	stderrLog := relayerLogWriter(r.log, r.events, rep, r.Name(), "stderr")
+	r.logs = []*lineWriter{stdoutLog, stderrLog}
+	proc.Stdout = io.MultiWriter(&lockedWriter{mu: &r.mu, w: r.stdout}, stdoutLog)
+	proc.Stderr = io.MultiWriter(&lockedWriter{mu: &r.mu, w: r.stderr}, stderrLog)
+
+	if err := proc.Start(); err != nil {
+		return fmt.Errorf("failed to start relayer: %w", err)
+	}
+
+	r.running = proc
+	r.runCmd = cmd
+	r.runPaths = pathNames
+	r.startedAt = time.Now()
+	r.done = make(chan error, 1)
+	go func() { r.done <- proc.Wait() }()
+
+	return nil
+}
+
+// Events returns the structured log lines of the relayer, collected while it is running.
+// Events are kept across restarts of the relayer.
+func (r *LocalRelayer) Events() *RelayerEvents {
+	return r.events
+}
+
+// PauseRelayer stops the running relayer process with SIGSTOP.
+func (r *LocalRelayer) PauseRelayer(ctx context.Context, rep ibc.RelayerExecReporter) error {
+	return r.signal(syscall.SIGSTOP)
+}
+
+// ResumeRelayer continues the relayer process stopped by PauseRelayer.
+func (r *LocalRelayer) ResumeRelayer(ctx context.Context, rep ibc.RelayerExecReporter) error {
+	return r.signal(syscall.SIGCONT)
+}
+
+func (r *LocalRelayer) signal(sig os.Signal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running == nil {
+		return fmt.Errorf("relayer is not running")
+	}
+	if err := r.running.Process.Signal(sig); err != nil {
+		return fmt.Errorf("failed to signal relayer: %w", err)
+	}
+	return nil
+}
+
+func (r *LocalRelayer) StopRelayer(ctx context.Context, rep ibc.RelayerExecReporter) error {
+	r.mu.Lock()
+	proc, done, logs := r.running, r.done, r.logs
+	r.mu.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+
+	// A paused relayer would not handle the interrupt.
+	_ = proc.Process.Signal(syscall.SIGCONT)
+	if err := proc.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to interrupt relayer: %w", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		_ = proc.Process.Kill()
+		<-done
+	case <-ctx.Done():
+		_ = proc.Process.Kill()
+		<-done
+	}
+
+	// The output of the process is fully copied once it has exited.
+	for _, l := range logs {
+		l.Flush()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stdout, stderr := r.stdout.String(), r.stderr.String()
+	rep.TrackRelayerExec(
+		r.Name(),
+		r.runCmd,
+		stdout, stderr,
+		proc.ProcessState.ExitCode(),
+		r.startedAt,
+		time.Now(),
+		nil,
+	)
+
+	r.log.Debug(
+		fmt.Sprintf("Stopped local relayer\nstdout:\n%s\nstderr:\n%s", stdout, stderr),
+		zap.Int("pid", proc.Process.Pid),
+	)
+
+	r.running = nil
+	r.runCmd = nil
+	r.runPaths = nil
+	r.logs = nil
+	r.done = nil
+
+	return nil
+}
+
+// UpdateHostAddresses implements ibc.HostAddressUpdater. The addresses are replaced in the configuration files
+// of the home directory, and a running relayer is restarted on the same paths to read them.
+func (r *LocalRelayer) UpdateHostAddresses(ctx context.Context, rep ibc.RelayerExecReporter, old, new ibc.HostAddresses) error {
+	if err := rewriteHostAddresses(r.HomeDir(), old, new); err != nil {
+		return fmt.Errorf("failed to update host addresses of relayer %s: %w", r.Name(), err)
+	}
+
+	r.mu.Lock()
+	running, paths := r.running != nil, r.runPaths
+	r.mu.Unlock()
+	if !running {
+		return nil
+	}
+	if err := r.StopRelayer(ctx, rep); err != nil {
+		return err
+	}
+	return r.StartRelayer(ctx, rep, paths...)
+}
+
+// command builds the host process for cmd.
+func (r *LocalRelayer) command(ctx context.Context, cmd []string, env []string) *exec.Cmd {
+	proc := exec.CommandContext(ctx, r.binary, cmd[1:]...)
+	proc.Env = append(os.Environ(), env...)
+	proc.Dir = r.HomeDir()
+	return proc
+}
+
+func (r *LocalRelayer) Name() string {
+	return r.c.Name() + "-local-" + dockerutil.SanitizeContainerName(r.testName)
+}
+
+// HomeDir returns the home directory of the relayer on the host filesystem.
+func (r *LocalRelayer) HomeDir() string {
+	return r.homeDir
+}
+
+// UseDockerNetwork reports false, the relayer connects to the host ports of the chains.
+func (r *LocalRelayer) UseDockerNetwork() bool {
+	return false
+}
+
+// lockedWriter serializes writes from the relayer process with reads in StopRelayer.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  *bytes.Buffer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// rewriteHostAddresses replaces the old host addresses with the new ones in the configuration files under dir.
+// An address followed by another digit is left untouched, as it is the address of another port.
+func rewriteHostAddresses(dir string, old, new ibc.HostAddresses) error {
+	replacements := make(map[string]string, 3)
+	var alternatives []string
+	for _, r := range [][2]string{{old.RPC, new.RPC}, {old.GRPC, new.GRPC}, {old.API, new.API}} {
+		// An address without port is not exposed.
+		if r[0] == "" || r[0] == r[1] || strings.HasSuffix(r[0], "/") || strings.HasSuffix(r[0], ":") {
+			continue
+		}
+		replacements[r[0]] = r[1]
+		alternatives = append(alternatives, regexp.QuoteMeta(r[0]))
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	// All the addresses are replaced in a single pass, so a new address is never replaced again.
+	re := regexp.MustCompile(`(?:` + strings.Join(alternatives, "|") + `)(?:\D|$)`)
+	rewrite := func(m string) string {
+		for o, n := range replacements {
+			if strings.HasPrefix(m, o) && len(m)-len(o) <= 1 {
+				return n + m[len(o):]
+			}
+		}
+		return m
+	}
+
+	return filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch filepath.Ext(name) {
+		case ".yaml", ".yml", ".json", ".toml":
+		default:
+			return nil
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		rewritten := re.ReplaceAllStringFunc(string(content), rewrite)
+		if rewritten == string(content) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(name, []byte(rewritten), info.Mode().Perm())
+	})
+}
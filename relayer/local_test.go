@@ -0,0 +1,66 @@
+package relayer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/relayer"
+	rly "github.com/decentrio/rollup-e2e-testing/relayer/rly"
+)
+
+func TestLocalRelayerUpdateHostAddresses(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rep := ibc.NopRelayerExecReporter{}
+
+	// The relayer binary records its starts in its home directory, and keeps running once started.
+	binary := filepath.Join(t.TempDir(), "rly")
+	require.NoError(t, os.WriteFile(binary, []byte("#!/bin/sh\n"+
+		"if [ \"$1\" = start ]; then echo start >> starts; exec sleep 60; fi\n"), 0o755))
+	home := t.TempDir()
+	r, err := rly.NewLocalCosmosRelayer(zaptest.NewLogger(t), t.Name(), "rly", relayer.LocalBinary(binary), relayer.HomeDir(home))
+	require.NoError(t, err)
+
+	config := filepath.Join(home, "config", "config.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(config), 0o755))
+	require.NoError(t, os.WriteFile(config, []byte(""+
+		"hub:\n  rpc-addr: http://127.0.0.1:3276\n  grpc-addr: 127.0.0.1:3277\n"+
+		"rollapp:\n  rpc-addr: http://127.0.0.1:32760\n  grpc-addr: 127.0.0.1:32761\n"), 0o644))
+
+	require.NoError(t, r.StartRelayer(ctx, rep, "hub-ra"))
+	t.Cleanup(func() { _ = r.StopRelayer(context.Background(), rep) })
+	waitForStarts(t, home, 1)
+
+	// The new RPC port of the node is its previous gRPC port.
+	require.NoError(t, r.UpdateHostAddresses(ctx, rep,
+		ibc.HostAddresses{RPC: "http://127.0.0.1:3276", GRPC: "127.0.0.1:3277", API: "http://"},
+		ibc.HostAddresses{RPC: "http://127.0.0.1:3277", GRPC: "127.0.0.1:3278", API: "http://"},
+	))
+
+	// Only the addresses of the node are replaced, each once.
+	content, err := os.ReadFile(config)
+	require.NoError(t, err)
+	require.Equal(t, ""+
+		"hub:\n  rpc-addr: http://127.0.0.1:3277\n  grpc-addr: 127.0.0.1:3278\n"+
+		"rollapp:\n  rpc-addr: http://127.0.0.1:32760\n  grpc-addr: 127.0.0.1:32761\n", string(content))
+
+	// The running relayer is restarted to read them.
+	waitForStarts(t, home, 2)
+}
+
+// waitForStarts waits for the relayer binary to record n starts in home.
+func waitForStarts(t *testing.T, home string, n int) {
+	require.Eventually(t, func() bool {
+		starts, err := os.ReadFile(filepath.Join(home, "starts"))
+		return err == nil && strings.Count(string(starts), "start") == n
+	}, 10*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,53 @@
+package relayer
+
+import (
+	"context"
+
+	"github.com/decentrio/rollup-e2e-testing/netchaos"
+)
+
+// ContainerIDs returns the IDs of the containers of the running relayer and of its exec container,
+// so the relayer can be targeted by network faults.
+func (r *DockerRelayer) ContainerIDs() []string {
+	var ids []string
+	r.mu.Lock()
+	if r.containerLifecycle != nil {
+		ids = append(ids, r.containerLifecycle.ContainerID())
+	}
+	r.mu.Unlock()
+
+	r.execMu.Lock()
+	defer r.execMu.Unlock()
+	if r.execContainer != nil {
+		ids = append(ids, r.execContainer.ContainerID())
+	}
+	return ids
+}
+
+// NetworkChaos returns the fault injector of the docker network of the relayer.
+func (r *DockerRelayer) NetworkChaos() *netchaos.Network {
+	r.chaosMu.Lock()
+	defer r.chaosMu.Unlock()
+
+	if r.chaos == nil {
+		r.chaos = netchaos.NewNetwork(r.log, r.client, r.testName, r.networkID)
+	}
+	return r.chaos
+}
+
+// DisconnectNetwork partitions the relayer from the chains, to test how it recovers once reconnected.
+// A relayer started after the partition is connected to the network.
+func (r *DockerRelayer) DisconnectNetwork(ctx context.Context) error {
+	return r.NetworkChaos().Disconnect(ctx, r)
+}
+
+// ReconnectNetwork reverts DisconnectNetwork.
+func (r *DockerRelayer) ReconnectNetwork(ctx context.Context) error {
+	return r.NetworkChaos().Reconnect(ctx, r)
+}
+
+// ImpairNetwork applies latency and packet loss to the traffic sent by the relayer.
+// A zero imp removes the impairment.
+func (r *DockerRelayer) ImpairNetwork(ctx context.Context, imp netchaos.Impairment) error {
+	return r.NetworkChaos().Impair(ctx, imp, r)
+}
@@ -66,3 +66,16 @@ func StartupFlags(flags ...string) RelayerOption {
 }
 
 func (opt RelayerOptionExtraStartFlags) relayerOption() {}
+
+// RelayerOptionLocalBinary sets the relayer binary run by a LocalRelayer.
+type RelayerOptionLocalBinary struct {
+	Path string
+}
+
+// LocalBinary overrides the relayer binary run on the host by a LocalRelayer,
+// either a path or a name looked up in PATH.
+func LocalBinary(path string) RelayerOption {
+	return RelayerOptionLocalBinary{Path: path}
+}
+
+func (opt RelayerOptionLocalBinary) relayerOption() {}
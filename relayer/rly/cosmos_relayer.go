@@ -4,13 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 	"github.com/decentrio/rollup-e2e-testing/ibc"
 	"github.com/decentrio/rollup-e2e-testing/relayer"
-	"github.com/docker/docker/client"
 	"go.uber.org/zap"
 )
 
@@ -24,7 +25,7 @@ type CosmosRelayer struct {
 	*relayer.DockerRelayer
 }
 
-func NewCosmosRelayer(log *zap.Logger, testName string, cli *client.Client, relayerName, networkID string, options ...relayer.RelayerOption) *CosmosRelayer {
+func NewCosmosRelayer(log *zap.Logger, testName string, cli dockerutil.Runtime, relayerName, networkID string, options ...relayer.RelayerOption) *CosmosRelayer {
 	c := commander{log: log}
 	for _, opt := range options {
 		switch o := opt.(type) {
@@ -44,6 +45,29 @@ func NewCosmosRelayer(log *zap.Logger, testName string, cli *client.Client, rela
 	return r
 }
 
+// LocalCosmosRelayer is the ibc.Relayer implementation for github.com/cosmos/relayer
+// running the rly binary installed on the host, one process per command.
+type LocalCosmosRelayer struct {
+	// Embedded LocalRelayer so commands just work.
+	*relayer.LocalRelayer
+}
+
+func NewLocalCosmosRelayer(log *zap.Logger, testName, relayerName string, options ...relayer.RelayerOption) (*LocalCosmosRelayer, error) {
+	c := commander{log: log}
+	for _, opt := range options {
+		switch o := opt.(type) {
+		case relayer.RelayerOptionExtraStartFlags:
+			c.extraStartFlags = o.Flags
+		}
+	}
+	lr, err := relayer.NewLocalRelayer(context.TODO(), log, testName, relayerName, c, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalCosmosRelayer{LocalRelayer: lr}, nil
+}
+
 type CosmosRelayerChainConfig struct {
 	Type  string `json:"type"`
 	Value Value  `json:"value"`
@@ -151,7 +175,7 @@ func (commander) GetAddress(chainID, keyName, coinType, homeDir string) []string
 }
 
 func (commander) CreateChannel(pathName string, opts ibc.CreateChannelOptions, homeDir string) []string {
-	return []string{
+	cmd := []string{
 		"rly", "tx", "channel", pathName,
 		"--src-port", opts.SourcePortName,
 		"--dst-port", opts.DestPortName,
@@ -160,21 +184,56 @@ func (commander) CreateChannel(pathName string, opts ibc.CreateChannelOptions, h
 		"--max-retries", "30", "--timeout", "40s", "--debug",
 		"--home", homeDir,
 	}
+	if opts.Override {
+		cmd = append(cmd, "--override")
+	}
+	return cmd
 }
 
-func (commander) CreateClients(pathName string, opts ibc.CreateClientOptions, homeDir string) []string {
+func (commander) LinkConnection(pathName string, clientOpts ibc.CreateClientOptions, homeDir string) []string {
+	cmd := []string{
+		"rly", "tx", "connection", pathName, "--max-retries", "30", "--timeout", "40s", "--debug",
+		"--home", homeDir,
+	}
+	if clientOpts.TrustingPeriod != "" {
+		cmd = append(cmd, "--client-tp", clientOpts.TrustingPeriod)
+	}
+	return cmd
+}
+
+func (commander) CloseChannel(pathName, channelID, portID, homeDir string) []string {
 	return []string{
+		"rly", "tx", "channel-close", pathName, channelID, portID,
+		"--max-retries", "30", "--timeout", "40s", "--debug",
+		"--home", homeDir,
+	}
+}
+
+// UpgradeChannel returns nil, rly has no command for the channel upgrade handshake.
+func (commander) UpgradeChannel(pathName, channelID, portID string, opts ibc.ChannelUpgradeOptions, homeDir string) []string {
+	return nil
+}
+
+func (commander) CreateClients(pathName string, opts ibc.CreateClientOptions, homeDir string) []string {
+	cmd := []string{
 		"rly", "tx", "clients", pathName, "--max-clock-drift", "70m", "--debug",
 		"--home", homeDir,
 	}
+	if opts.TrustingPeriod != "" {
+		cmd = append(cmd, "--client-tp", opts.TrustingPeriod)
+	}
+	return cmd
 }
 
-// passing a value of 0 for customeClientTrustingPeriod will use default
-func (commander) CreateClient(pathName, homeDir, customeClientTrustingPeriod string) []string {
-	return []string{
-		"rly", "tx", "client", pathName,
+func (commander) CreateClient(srcChainID, dstChainID, pathName string, opts ibc.CreateClientOptions, homeDir string) []string {
+	cmd := []string{
+		"rly", "tx", "client", srcChainID, dstChainID, pathName, "--max-clock-drift", "70m", "--debug",
 		"--home", homeDir,
 	}
+	if opts.TrustingPeriod != "" {
+		cmd = append(cmd, "--client-tp", opts.TrustingPeriod)
+	}
+	return cmd
 }
 
 func (commander) CreateConnections(pathName string, homeDir string) []string {
@@ -203,6 +262,30 @@ func (commander) Flush(pathName, channelID, homeDir string) []string {
 	return cmd
 }
 
+// RelayPackets relays the packets of the given sequences only when sequences is not empty,
+// which needs an rly release supporting the --seqs flag of relay-packets.
+func (commander) RelayPackets(pathName, channelID string, sequences []uint64, homeDir string) []string {
+	cmd := []string{
+		"rly", "tx", "relay-packets", pathName, channelID,
+		"--home", homeDir,
+	}
+	if len(sequences) > 0 {
+		seqs := make([]string, len(sequences))
+		for i, seq := range sequences {
+			seqs[i] = strconv.FormatUint(seq, 10)
+		}
+		cmd = append(cmd, "--seqs", strings.Join(seqs, ","))
+	}
+	return cmd
+}
+
+func (commander) RelayAcknowledgements(pathName, channelID, homeDir string) []string {
+	return []string{
+		"rly", "tx", "relay-acknowledgements", pathName, channelID,
+		"--home", homeDir,
+	}
+}
+
 func (commander) GeneratePath(srcChainID, dstChainID, pathName, homeDir string) []string {
 	return []string{
 		"rly", "paths", "new", srcChainID, dstChainID, pathName,
@@ -219,6 +302,17 @@ func (commander) UpdatePath(pathName, homeDir string, filter ibc.ChannelFilter)
 	}
 }
 
+func (commander) SetPathIDs(pathName, homeDir string, ids ibc.PathIDs) []string {
+	return []string{
+		"rly", "paths", "update", pathName,
+		"--home", homeDir,
+		"--src-client-id", ids.SrcClientID,
+		"--src-connection-id", ids.SrcConnectionID,
+		"--dst-client-id", ids.DstClientID,
+		"--dst-connection-id", ids.DstConnectionID,
+	}
+}
+
 func (commander) GetChannels(chainID, homeDir string) []string {
 	return []string{
 		"rly", "q", "channels", chainID,
@@ -265,6 +359,8 @@ func (c commander) StartRelayer(homeDir string, pathNames ...string) []string {
 	cmd := []string{
 		"rly", "start", "--debug",
 		"--home", homeDir,
+		// JSON lines are collected as relayer events, see relayer.ParseRlyLogLine.
+		"--log-format", "json",
 	}
 	cmd = append(cmd, c.extraStartFlags...)
 	cmd = append(cmd, pathNames...)
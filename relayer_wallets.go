@@ -0,0 +1,219 @@
+package rollupe2etesting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"go.uber.org/zap"
+)
+
+// RelayerWallet returns the wallet relayer r signs with on chain, and whether it exists.
+// Wallets are set during Build.
+func (s *Setup) RelayerWallet(r ibc.Relayer, chain ibc.Chain) (ibc.Wallet, bool) {
+	wallet, ok := s.relayerWallets[relayerChain{R: r, C: chain}]
+	return wallet, ok
+}
+
+// RelayerWalletMonitorOptions configures MonitorRelayerWallets.
+type RelayerWalletMonitorOptions struct {
+	// How often the balances are checked. Defaults to 10 seconds.
+	Interval time.Duration
+
+	// If set, a wallet with a balance of the chain denom below Threshold is sent TopUp.
+	// Top-ups are sent from a key of the monitor, funded from the faucet of every chain when monitoring starts,
+	// so they do not race with the faucet transactions of the test.
+	Threshold math.Int
+	TopUp     math.Int
+
+	// Amount funded to the key of the monitor on every chain.
+	// Defaults to 10 top-ups for every relayer wallet on the chain.
+	Budget math.Int
+}
+
+// RelayerWalletSpend is the native denom spent by a relayer wallet on a chain
+// and the amount it received from the faucet while monitored.
+type RelayerWalletSpend struct {
+	Relayer string
+	ChainID string
+	Address string
+	Denom   string
+
+	Spent    math.Int
+	ToppedUp math.Int
+}
+
+// RelayerWalletMonitor tracks the balances of the relayer wallets of a Setup.
+type RelayerWalletMonitor struct {
+	s    *Setup
+	opts RelayerWalletMonitorOptions
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// funders are the keys of the monitor sending top-ups, by chain.
+	funders map[ibc.Chain]ibc.Wallet
+
+	mu      sync.Mutex
+	spends  map[relayerChain]*RelayerWalletSpend
+	balance map[relayerChain]math.Int
+}
+
+// MonitorRelayerWallets tracks the gas spent by every relayer wallet in the background until the end of the test,
+// topping up wallets running low if configured. The spend of each wallet is logged when the test finishes.
+// It must be called after Build, from the test goroutine.
+func (s *Setup) MonitorRelayerWallets(t *testing.T, ctx context.Context, opts RelayerWalletMonitorOptions) *RelayerWalletMonitor {
+	if opts.Interval == 0 {
+		opts.Interval = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	m := &RelayerWalletMonitor{
+		s:    s,
+		opts: opts,
+
+		cancel: cancel,
+		done:   make(chan struct{}),
+
+		funders: make(map[ibc.Chain]ibc.Wallet),
+		spends:  make(map[relayerChain]*RelayerWalletSpend, len(s.relayerWallets)),
+		balance: make(map[relayerChain]math.Int, len(s.relayerWallets)),
+	}
+
+	if !opts.Threshold.IsNil() && !opts.TopUp.IsNil() {
+		if err := m.fund(ctx); err != nil {
+			cancel()
+			t.Fatalf("Failed to fund relayer wallet top-ups: %v", err)
+		}
+	}
+	for rc, wallet := range s.relayerWallets {
+		m.spends[rc] = &RelayerWalletSpend{
+			Relayer:  s.relayers[rc.R],
+			ChainID:  rc.C.Config().ChainID,
+			Address:  wallet.FormattedAddress(),
+			Denom:    rc.C.Config().Denom,
+			Spent:    math.ZeroInt(),
+			ToppedUp: math.ZeroInt(),
+		}
+	}
+
+	go m.run(ctx)
+
+	t.Cleanup(func() {
+		for _, spend := range m.Stop() {
+			t.Logf("Relayer %s spent %s%s on %s (topped up %s)",
+				spend.Relayer, spend.Spent, spend.Denom, spend.ChainID, spend.ToppedUp)
+		}
+	})
+
+	return m
+}
+
+// fund creates the key sending top-ups on every chain with relayer wallets and funds it from the faucet.
+func (m *RelayerWalletMonitor) fund(ctx context.Context) error {
+	wallets := make(map[ibc.Chain]int64)
+	for rc := range m.s.relayerWallets {
+		wallets[rc.C]++
+	}
+
+	for chain, n := range wallets {
+		budget := m.opts.Budget
+		if budget.IsNil() {
+			budget = m.opts.TopUp.MulRaw(10 * n)
+		}
+		funder, err := GetAndFundTestUserWithMnemonic(ctx, "relayer-topup", "", budget, chain)
+		if err != nil {
+			return fmt.Errorf("chain %s: %w", chain.Config().ChainID, err)
+		}
+		m.funders[chain] = funder
+	}
+	return nil
+}
+
+func (m *RelayerWalletMonitor) run(ctx context.Context) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check records the spend of every wallet since the last check and tops up the wallets running low.
+func (m *RelayerWalletMonitor) check(ctx context.Context) {
+	for rc, wallet := range m.s.relayerWallets {
+		denom := rc.C.Config().Denom
+		bal, err := rc.C.GetBalance(ctx, wallet.FormattedAddress(), denom)
+		if err != nil {
+			if ctx.Err() == nil {
+				m.s.log.Info("Failed to get relayer wallet balance", zap.String("chain_id", rc.C.Config().ChainID), zap.Error(err))
+			}
+			continue
+		}
+
+		m.mu.Lock()
+		spend := m.spends[rc]
+		if prev, ok := m.balance[rc]; ok && bal.LT(prev) {
+			spend.Spent = spend.Spent.Add(prev.Sub(bal))
+		}
+		m.balance[rc] = bal
+		m.mu.Unlock()
+
+		if m.opts.Threshold.IsNil() || m.opts.TopUp.IsNil() || bal.GTE(m.opts.Threshold) {
+			continue
+		}
+
+		if err := rc.C.SendFunds(ctx, m.funders[rc.C].KeyName(), ibc.WalletData{
+			Address: wallet.FormattedAddress(),
+			Amount:  m.opts.TopUp,
+			Denom:   denom,
+		}); err != nil {
+			m.s.log.Info("Failed to top up relayer wallet", zap.String("chain_id", rc.C.Config().ChainID), zap.Error(err))
+			continue
+		}
+
+		m.mu.Lock()
+		spend.ToppedUp = spend.ToppedUp.Add(m.opts.TopUp)
+		m.balance[rc] = bal.Add(m.opts.TopUp)
+		m.mu.Unlock()
+	}
+}
+
+// Spend returns the spend of every relayer wallet so far, sorted by relayer and chain.
+func (m *RelayerWalletMonitor) Spend() []RelayerWalletSpend {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	spends := make([]RelayerWalletSpend, 0, len(m.spends))
+	for _, spend := range m.spends {
+		spends = append(spends, *spend)
+	}
+	sort.Slice(spends, func(i, j int) bool {
+		if spends[i].Relayer != spends[j].Relayer {
+			return spends[i].Relayer < spends[j].Relayer
+		}
+		return spends[i].ChainID < spends[j].ChainID
+	})
+	return spends
+}
+
+// Stop stops monitoring and returns the final spend of every relayer wallet.
+// It is safe to call more than once.
+func (m *RelayerWalletMonitor) Stop() []RelayerWalletSpend {
+	m.cancel()
+	<-m.done
+	return m.Spend()
+}
@@ -1,12 +1,14 @@
 package rollupe2etesting
 
 import (
+	"errors"
 	"fmt"
+	"os/exec"
 
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 	"github.com/decentrio/rollup-e2e-testing/ibc"
 	"github.com/decentrio/rollup-e2e-testing/relayer"
 	rly "github.com/decentrio/rollup-e2e-testing/relayer/rly"
-	"github.com/docker/docker/client"
 	"go.uber.org/zap"
 )
 
@@ -19,7 +21,7 @@ type RelayerFactory interface {
 	// Build returns a Relayer associated with the given arguments.
 	Build(
 		t TestName,
-		cli *client.Client,
+		cli dockerutil.Runtime,
 		relayerName, networkID string,
 	) ibc.Relayer
 
@@ -43,7 +45,7 @@ func NewBuiltinRelayerFactory(impl ibc.RelayerImplementation, logger *zap.Logger
 // Build returns a relayer chosen depending on f.impl.
 func (f builtinRelayerFactory) Build(
 	t TestName,
-	cli *client.Client,
+	cli dockerutil.Runtime,
 	relayerName, networkID string,
 ) ibc.Relayer {
 	switch f.impl {
@@ -56,11 +58,34 @@ func (f builtinRelayerFactory) Build(
 			networkID,
 			f.options...,
 		)
+	case ibc.CosmosRlyLocal:
+		r, err := rly.NewLocalCosmosRelayer(f.log, t.Name(), relayerName, f.options...)
+		if err != nil {
+			failLocalRelayer(t, err)
+		}
+		return r
 	default:
 		panic(fmt.Errorf("RelayerImplementation %v unknown", f.impl))
 	}
 }
 
+// failLocalRelayer ends the test t when the local relayer cannot be built:
+// the test is skipped when the relayer binary is not installed on the host, and fails otherwise.
+// It panics if t is not a testing.TB.
+func failLocalRelayer(t TestName, err error) {
+	tb, ok := t.(interface {
+		Skipf(format string, args ...any)
+		Fatalf(format string, args ...any)
+	})
+	if !ok {
+		panic(err)
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		tb.Skipf("Skipping test using the local relayer: %v", err)
+	}
+	tb.Fatalf("Failed to build local relayer: %v", err)
+}
+
 func (f builtinRelayerFactory) Name() string {
 	switch f.impl {
 	case ibc.CosmosRly:
@@ -74,6 +99,8 @@ func (f builtinRelayerFactory) Name() string {
 			}
 		}
 		return "rly@" + rly.DefaultContainerVersion
+	case ibc.CosmosRlyLocal:
+		return "rly-local"
 	default:
 		panic(fmt.Errorf("RelayerImplementation %v unknown", f.impl))
 	}
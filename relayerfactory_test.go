@@ -0,0 +1,51 @@
+package rollupe2etesting
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/relayer"
+)
+
+// exitingT records how a test ended, exiting the goroutine like testing.T does.
+type exitingT struct {
+	name    string
+	skipped string
+	failed  string
+}
+
+func (t *exitingT) Name() string { return t.name }
+
+func (t *exitingT) Skipf(format string, args ...any) {
+	t.skipped = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func (t *exitingT) Fatalf(format string, args ...any) {
+	t.failed = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestBuiltinRelayerFactoryLocalBinaryMissing(t *testing.T) {
+	t.Parallel()
+
+	f := NewBuiltinRelayerFactory(ibc.CosmosRlyLocal, zaptest.NewLogger(t),
+		relayer.LocalBinary("rly-not-installed"), relayer.HomeDir(t.TempDir()))
+
+	rt := &exitingT{name: t.Name()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Build(rt, dockerutil.NewFakeRuntime(), "rly", "net")
+	}()
+	<-done
+
+	require.Contains(t, rt.skipped, "rly-not-installed")
+	require.Empty(t, rt.failed)
+}
@@ -5,16 +5,20 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"cosmossdk.io/math"
 	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/netchaos"
 	"github.com/decentrio/rollup-e2e-testing/testreporter"
 	"github.com/decentrio/rollup-e2e-testing/testutil"
-	"github.com/docker/docker/client"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -35,7 +39,7 @@ type Setup struct {
 	links map[relayerPath]Link
 
 	// Map of relayer-chain pairs to address and mnemonic, set during Build().
-	// Not yet exposed through any exported API.
+	// Exposed through RelayerWallet.
 	relayerWallets map[relayerChain]ibc.Wallet
 
 	// Map of chain to additional genesis wallets to include at chain start.
@@ -56,6 +60,9 @@ type Link struct {
 	// If a zero value initialization is used, e.g. CreateChannelOptions{},
 	// then the default values will be used via ibc.DefaultChannelOpts.
 	createChannelOpts ibc.CreateChannelOptions
+
+	// Relayers relaying the path concurrently with the relayer that linked it.
+	sharedRelayers []ibc.Relayer
 }
 
 // NewSetup returns a new Setup.
@@ -188,6 +195,10 @@ type InterchainLink struct {
 	// If a zero value initialization is used, e.g. CreateChannelOptions{},
 	// then the default values will be used via ibc.DefaultChannelOpts.
 	CreateChannelOpts ibc.CreateChannelOptions
+
+	// Optional. Additional relayers relaying the same path concurrently with Relayer,
+	// each with its own funded wallet. Only Relayer creates the clients, connections and channels.
+	SharedRelayers []ibc.Relayer
 }
 
 // AddLink adds the given link to the Setup.
@@ -218,10 +229,23 @@ func (s *Setup) AddLink(link InterchainLink) *Setup {
 		panic(fmt.Errorf("relayer %q already has a path named %q", key.Relayer, key.Path))
 	}
 
+	for _, r := range link.SharedRelayers {
+		if _, exists := s.relayers[r]; !exists {
+			panic(fmt.Errorf("relayer %v was never added to Setup", r))
+		}
+		if r == link.Relayer {
+			panic(fmt.Errorf("relayer %q cannot share its own path %q", s.relayers[r], link.Path))
+		}
+		if _, exists := s.links[relayerPath{Relayer: r, Path: link.Path}]; exists {
+			panic(fmt.Errorf("relayer %q already has a path named %q", s.relayers[r], link.Path))
+		}
+	}
+
 	s.links[key] = Link{
 		chains:            [2]ibc.Chain{link.Chain1, link.Chain2},
 		createChannelOpts: link.CreateChannelOpts,
 		createClientOpts:  link.CreateClientOpts,
+		sharedRelayers:    link.SharedRelayers,
 	}
 	return s
 }
@@ -230,7 +254,7 @@ func (s *Setup) AddLink(link InterchainLink) *Setup {
 type InterchainBuildOptions struct {
 	TestName string
 
-	Client    *client.Client
+	Client    dockerutil.Runtime
 	NetworkID string
 
 	// If set, s.Build does not create paths or links in the relayer,
@@ -244,6 +268,11 @@ type InterchainBuildOptions struct {
 
 	// If set, saves block history to a sqlite3 database to aid debugging.
 	BlockDatabaseFile string
+
+	// Optional. If set along with BlockDatabaseFile, samples the CPU, memory, network and block IO usage
+	// of every node, sidecar and relayer container at this interval into the database,
+	// e.g. to track performance regressions of chain binaries in CI.
+	ContainerStatsInterval time.Duration
 }
 
 // Build starts all the chains and configures the relayers associated with the Setup.
@@ -257,6 +286,11 @@ func (s *Setup) Build(ctx context.Context, rep *testreporter.RelayerExecReporter
 	}
 	s.cs = newChainSet(s.log, chains)
 
+	// Check all the images up front, so a missing image does not surface later as a container error.
+	if err := s.preflightImages(ctx, opts.Client); err != nil {
+		return fmt.Errorf("failed image preflight: %w", err)
+	}
+
 	// Initialize the chains (pull docker images, etc.).
 	if err := s.cs.Initialize(ctx, opts.TestName, opts.Client, opts.NetworkID); err != nil {
 		return fmt.Errorf("failed to initialize chains: %w", err)
@@ -286,7 +320,7 @@ func (s *Setup) Build(ctx context.Context, rep *testreporter.RelayerExecReporter
 
 	}
 
-	if err := s.cs.TrackBlocks(ctx, opts.TestName, opts.BlockDatabaseFile, opts.GitSha); err != nil {
+	if err := s.cs.TrackBlocks(ctx, opts.TestName, opts.BlockDatabaseFile, opts.GitSha, opts.Client, opts.ContainerStatsInterval); err != nil {
 		return fmt.Errorf("failed to track blocks: %w", err)
 	}
 
@@ -294,9 +328,10 @@ func (s *Setup) Build(ctx context.Context, rep *testreporter.RelayerExecReporter
 		// Error already wrapped with appropriate detail.
 		return err
 	}
+	s.watchHostAddresses(rep)
 
 	for r := range s.relayerChains() {
-		filePath := "/tmp/" + s.relayers[r] + "/config/config.yaml"
+		filePath := filepath.Join(s.relayerHostHomeDir(r), "config", "config.yaml")
 
 		content, err := os.ReadFile(filePath)
 		if err != nil {
@@ -371,7 +406,111 @@ func (s *Setup) Build(ctx context.Context, rep *testreporter.RelayerExecReporter
 		time.Sleep(30 * time.Second)
 	}
 
-	return eg.Wait()
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	return s.shareLinks(ctx, rep)
+}
+
+// shareLinks points the shared relayers of every link at the clients and connections
+// created by the relayer that linked the path.
+func (s *Setup) shareLinks(ctx context.Context, rep *testreporter.RelayerExecReporter) error {
+	for rp, link := range s.links {
+		if len(link.sharedRelayers) == 0 {
+			continue
+		}
+		c0 := link.chains[0]
+		c1 := link.chains[1]
+
+		ids, err := ibc.GetPathIDs(ctx, rp.Relayer, rep, c0.Config().ChainID, c1.Config().ChainID)
+		if err != nil {
+			return fmt.Errorf("failed to get ids of path %s on relayer %s: %w", rp.Path, s.relayers[rp.Relayer], err)
+		}
+
+		for _, r := range link.sharedRelayers {
+			if err := r.GeneratePath(ctx, rep, c0.Config().ChainID, c1.Config().ChainID, rp.Path); err != nil {
+				return fmt.Errorf(
+					"failed to generate shared path %s on relayer %s between chains %s and %s: %w",
+					rp.Path, s.relayers[r], s.chains[c0], s.chains[c1], err,
+				)
+			}
+			if err := r.SetPathIDs(ctx, rep, rp.Path, ids); err != nil {
+				return fmt.Errorf("failed to share path %s with relayer %s: %w", rp.Path, s.relayers[r], err)
+			}
+		}
+	}
+	return nil
+}
+
+// preflightImages builds or pulls the images of all the chains and sidecars according to their configuration,
+// pulls the netchaos sidecar image if it is not present, and returns a dockerutil.MissingImagesError listing all the images that are not present.
+// The images of the chains and sidecars it ensured are recorded on s.cs, so the chains do not ensure them again.
+// It returns an error if chains configure the same image with different pull policies or builds.
+func (s *Setup) preflightImages(ctx context.Context, cli dockerutil.Runtime) error {
+	ensure := map[string]func() error{
+		dockerutil.BusyboxRef: func() error {
+			return dockerutil.PullImage(ctx, s.log, cli, dockerutil.BusyboxRef, dockerutil.PullIfNotPresent)
+		},
+		netchaos.DefaultSidecarImage.Ref(): func() error {
+			return dockerutil.PullImage(ctx, s.log, cli, netchaos.DefaultSidecarImage.Ref(), dockerutil.PullIfNotPresent)
+		},
+	}
+	chainImages := make(map[string]ibc.DockerImage)
+	for c := range s.chains {
+		images := append([]ibc.DockerImage{}, c.Config().Images...)
+		for _, sc := range c.Config().SidecarConfigs {
+			images = append(images, sc.Image)
+		}
+		for _, image := range images {
+			image := image
+			ref := image.Ref()
+			if prev, ok := chainImages[ref]; ok {
+				if prev.PullPolicy != image.PullPolicy || !reflect.DeepEqual(prev.Build, image.Build) {
+					return fmt.Errorf("image %s is configured with different pull policies or builds", ref)
+				}
+				continue
+			}
+			chainImages[ref] = image
+			ensure[ref] = func() error {
+				return image.Ensure(ctx, s.log, cli)
+			}
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		missing []dockerutil.MissingImage
+		ensured []string
+		wg      sync.WaitGroup
+	)
+	for ref, fn := range ensure {
+		ref, fn := ref, fn
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := fn()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				missing = append(missing, dockerutil.MissingImage{Ref: ref, Err: err})
+				return
+			}
+			if _, ok := chainImages[ref]; ok {
+				ensured = append(ensured, ref)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Strings(ensured)
+	s.cs.ensuredImages = ensured
+
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Ref < missing[j].Ref })
+	return &dockerutil.MissingImagesError{Images: missing}
 }
 
 // WithLog sets the logger on the interchain object.
@@ -473,6 +612,8 @@ func (s *Setup) configureRelayerKeys(ctx context.Context, rep *testreporter.Rela
 			if err != nil {
 				return fmt.Errorf("failed to add key to relayer %s for chain %s: %w", s.relayers[r], chainId, err)
 			}
+			// The relayer signs with the key it created, not the generated one.
+			s.relayerWallets[relayerChain{R: r, C: c}] = wallet
 
 			if failExpected {
 				fmt.Println("did not send fund")
@@ -492,6 +633,37 @@ func (s *Setup) configureRelayerKeys(ctx context.Context, rep *testreporter.Rela
 	return nil
 }
 
+// watchHostAddresses keeps the configuration of the relayers running on the host pointed at the nodes
+// of their chains, when the nodes restart on new host ports, see dockerutil.PortAllocationDocker.
+func (s *Setup) watchHostAddresses(rep ibc.RelayerExecReporter) {
+	for r, chains := range s.relayerChains() {
+		hu, ok := r.(ibc.HostAddressUpdater)
+		if !ok || r.UseDockerNetwork() {
+			continue
+		}
+		for _, c := range chains {
+			if hn, ok := c.(ibc.HostAddressNotifier); ok {
+				hn.OnHostAddressesChange(func(ctx context.Context, old, new ibc.HostAddresses) error {
+					return hu.UpdateHostAddresses(ctx, rep, old, new)
+				})
+			}
+		}
+	}
+}
+
+// relayerHostHomeDir returns the directory of the host holding the home directory of r.
+func (s *Setup) relayerHostHomeDir(r ibc.Relayer) string {
+	if hr, ok := r.(interface{ HostHomeDir() string }); ok {
+		// Docker relayers bind their home directory to the host, see relayer.DockerRelayer.Bind.
+		return hr.HostHomeDir()
+	}
+	if hr, ok := r.(interface{ HomeDir() string }); ok && !r.UseDockerNetwork() {
+		// Relayers running on the host keep their config in their home directory.
+		return hr.HomeDir()
+	}
+	return "/tmp/" + s.relayers[r]
+}
+
 // relayerChain is a tuple of a Relayer and a Chain.
 type relayerChain struct {
 	R ibc.Relayer
@@ -506,12 +678,13 @@ func (s *Setup) relayerChains() map[ibc.Relayer][]ibc.Chain {
 	uniq := make(map[ibc.Relayer]map[ibc.Chain]struct{}, len(s.relayers))
 
 	for rp, link := range s.links {
-		r := rp.Relayer
-		if uniq[r] == nil {
-			uniq[r] = make(map[ibc.Chain]struct{}, 2) // Adding at least 2 chains per relayer.
+		for _, r := range append([]ibc.Relayer{rp.Relayer}, link.sharedRelayers...) {
+			if uniq[r] == nil {
+				uniq[r] = make(map[ibc.Chain]struct{}, 2) // Adding at least 2 chains per relayer.
+			}
+			uniq[r][link.chains[0]] = struct{}{}
+			uniq[r][link.chains[1]] = struct{}{}
 		}
-		uniq[r][link.chains[0]] = struct{}{}
-		uniq[r][link.chains[1]] = struct{}{}
 	}
 
 	// Then convert the sets to slices.
@@ -601,10 +774,11 @@ func KeepDockerVolumesOnFailure(b bool) {
 	dockerutil.KeepVolumesOnFailure = b
 }
 
-// DockerSetup returns a new Docker Client and the ID of a configured network, associated with t.
+// DockerSetup returns a new container Runtime and the ID of a configured network, associated with t.
+// Options select the container engine, e.g. dockerutil.WithBackend(dockerutil.BackendPodman).
 //
 // If any part of the setup fails, t.Fatal is called.
-func DockerSetup(t dockerutil.DockerSetupTestingT) (*client.Client, string) {
+func DockerSetup(t dockerutil.DockerSetupTestingT, options ...dockerutil.DockerSetupOption) (dockerutil.Runtime, string) {
 	t.Helper()
-	return dockerutil.DockerSetup(t)
+	return dockerutil.DockerSetup(t, options...)
 }
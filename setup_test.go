@@ -0,0 +1,81 @@
+package rollupe2etesting
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/decentrio/rollup-e2e-testing/cosmos"
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/netchaos"
+)
+
+// pullCountingRuntime counts the image pulls by reference.
+type pullCountingRuntime struct {
+	dockerutil.Runtime
+
+	mu    sync.Mutex
+	pulls map[string]int
+}
+
+func (r *pullCountingRuntime) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	r.mu.Lock()
+	r.pulls[ref]++
+	r.mu.Unlock()
+	return r.Runtime.ImagePull(ctx, ref, options)
+}
+
+func testSetupChain(t *testing.T, chainID string, image ibc.DockerImage) *cosmos.CosmosChain {
+	return cosmos.NewCosmosChain(t.Name(), ibc.ChainConfig{
+		Type:    "hub-dym",
+		Name:    chainID,
+		ChainID: chainID,
+		Bin:     "dymd",
+		Images:  []ibc.DockerImage{image},
+	}, 1, 0, zaptest.NewLogger(t))
+}
+
+func TestPreflightImagesEnsuredOnce(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rt := &pullCountingRuntime{Runtime: dockerutil.NewFakeRuntime(), pulls: map[string]int{}}
+	image := ibc.DockerImage{Repository: "dymd", Version: "v1", UidGid: "1025:1025", PullPolicy: dockerutil.PullAlways}
+
+	s := NewSetup().WithLog(zaptest.NewLogger(t)).
+		AddChain(testSetupChain(t, "hub-1", image)).
+		AddChain(testSetupChain(t, "hub-2", image))
+	chains := make([]ibc.Chain, 0, len(s.chains))
+	for c := range s.chains {
+		chains = append(chains, c)
+	}
+	s.cs = newChainSet(s.log, chains)
+
+	require.NoError(t, s.preflightImages(ctx, rt))
+	require.Equal(t, []string{"dymd:v1"}, s.cs.ensuredImages)
+	require.NoError(t, s.cs.Initialize(ctx, t.Name(), rt, "net"))
+	require.Equal(t, 1, rt.pulls["dymd:v1"])
+	require.Equal(t, 1, rt.pulls[netchaos.DefaultSidecarImage.Ref()])
+}
+
+func TestPreflightImagesConflict(t *testing.T) {
+	t.Parallel()
+
+	image := ibc.DockerImage{Repository: "dymd", Version: "v1", PullPolicy: dockerutil.PullAlways}
+	local := image
+	local.PullPolicy = dockerutil.PullNever
+
+	s := NewSetup().WithLog(zaptest.NewLogger(t)).
+		AddChain(testSetupChain(t, "hub-1", image)).
+		AddChain(testSetupChain(t, "hub-2", local))
+	s.cs = newChainSet(s.log, nil)
+
+	err := s.preflightImages(context.Background(), dockerutil.NewFakeRuntime())
+	require.ErrorContains(t, err, "image dymd:v1 is configured with different pull policies or builds")
+}
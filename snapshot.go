@@ -0,0 +1,347 @@
+package rollupe2etesting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"golang.org/x/sync/errgroup"
+)
+
+// snapshotManifestFile is the file describing a snapshot, at the root of the snapshot directory.
+const snapshotManifestFile = "snapshot.json"
+
+// networkSnapshot is the manifest of a snapshot written by Setup.Snapshot.
+type networkSnapshot struct {
+	// Nodes of every chain by chain ID. The archives of the nodes of a chain are in a directory named after its ID.
+	Chains map[string][]ibc.NodeSnapshot
+
+	// Host addresses of every chain by chain ID, as configured in the relayers running on the host.
+	HostAddresses map[string]ibc.HostAddresses
+
+	// Relayers by name.
+	Relayers map[string]relayerSnapshot
+}
+
+func chainHostAddresses(c ibc.Chain) ibc.HostAddresses {
+	return ibc.HostAddresses{RPC: c.GetHostRPCAddress(), GRPC: c.GetHostGRPCAddress(), API: c.GetHostAPIAddress()}
+}
+
+type relayerSnapshot struct {
+	// Archive of the home directory of the relayer, relative to the snapshot directory.
+	Archive string
+
+	// Wallets of the relayer by chain ID.
+	Wallets map[string]walletSnapshot
+}
+
+type walletSnapshot struct {
+	KeyName      string
+	Address      string
+	AddressBytes []byte
+	Mnemonic     string
+}
+
+// restoredWallet is an ibc.Wallet restored from a snapshot.
+type restoredWallet struct {
+	w walletSnapshot
+}
+
+func (w restoredWallet) KeyName() string          { return w.w.KeyName }
+func (w restoredWallet) FormattedAddress() string { return w.w.Address }
+func (w restoredWallet) Mnemonic() string         { return w.w.Mnemonic }
+func (w restoredWallet) Address() []byte          { return w.w.AddressBytes }
+
+// Snapshot archives the network started by Build into dir, so that RestoreSetup can start
+// an identical network, with its clients, connections and channels, in other tests in seconds.
+//
+// Every node of every chain is stopped, then the home directories of all the nodes are archived
+// and the nodes start again. The home directories and wallets of the relayers, which must not be running,
+// are archived as well.
+// Every chain must implement ibc.Snapshotter.
+func (s *Setup) Snapshot(ctx context.Context, dir string) error {
+	if s.cs == nil {
+		return errors.New("snapshot must be taken after Build")
+	}
+
+	manifest := networkSnapshot{
+		Chains:        make(map[string][]ibc.NodeSnapshot, len(s.chains)),
+		HostAddresses: make(map[string]ibc.HostAddresses, len(s.chains)),
+		Relayers:      make(map[string]relayerSnapshot, len(s.relayers)),
+	}
+
+	snapshotters := make(map[string]ibc.Snapshotter, len(s.chains))
+	for c := range s.chains {
+		chainID := c.Config().ChainID
+		sc, ok := c.(ibc.Snapshotter)
+		if !ok {
+			return fmt.Errorf("chain %s does not support snapshots", chainID)
+		}
+		snapshotters[chainID] = sc
+	}
+	// Every node of every chain is stopped before any is archived, so the archives of all the chains
+	// are taken at the same point, e.g. with the same client updates on both sides of a link.
+	forEachChain := func(fn func(chainID string, sc ibc.Snapshotter) error) error {
+		var eg errgroup.Group
+		for chainID, sc := range snapshotters {
+			chainID, sc := chainID, sc
+			eg.Go(func() error { return fn(chainID, sc) })
+		}
+		return eg.Wait()
+	}
+
+	var mu sync.Mutex
+	err := forEachChain(func(chainID string, sc ibc.Snapshotter) error {
+		if err := sc.StopNodes(ctx); err != nil {
+			return fmt.Errorf("failed to stop chain %s: %w", chainID, err)
+		}
+		return nil
+	})
+	if err == nil {
+		err = forEachChain(func(chainID string, sc ibc.Snapshotter) error {
+			nodes, err := sc.SnapshotNodes(ctx, filepath.Join(dir, chainID))
+			if err != nil {
+				return fmt.Errorf("failed to snapshot chain %s: %w", chainID, err)
+			}
+			mu.Lock()
+			manifest.Chains[chainID] = nodes
+			mu.Unlock()
+			return nil
+		})
+	}
+	// The nodes are started again even if the snapshot failed.
+	startErr := forEachChain(func(chainID string, sc ibc.Snapshotter) error {
+		if err := sc.StartNodes(ctx); err != nil {
+			return fmt.Errorf("failed to start chain %s: %w", chainID, err)
+		}
+		return nil
+	})
+	if err := errors.Join(err, startErr); err != nil {
+		return err
+	}
+
+	// Recorded once the nodes started again, as in the configuration of the relayers running on the host.
+	for c := range s.chains {
+		manifest.HostAddresses[c.Config().ChainID] = chainHostAddresses(c)
+	}
+
+	for r, name := range s.relayers {
+		rs := relayerSnapshot{
+			Archive: name + ".tar",
+			Wallets: make(map[string]walletSnapshot),
+		}
+		for rc, w := range s.relayerWallets {
+			if rc.R == r {
+				rs.Wallets[rc.C.Config().ChainID] = walletSnapshot{
+					KeyName:      w.KeyName(),
+					Address:      w.FormattedAddress(),
+					AddressBytes: w.Address(),
+					Mnemonic:     w.Mnemonic(),
+				}
+			}
+		}
+
+		if err := writeDirArchive(filepath.Join(dir, rs.Archive), s.relayerHostHomeDir(r)); err != nil {
+			return fmt.Errorf("failed to snapshot relayer %s: %w", name, err)
+		}
+		manifest.Relayers[name] = rs
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, snapshotManifestFile), b, 0o644)
+}
+
+// RestoreSetup starts the network of s from the snapshot in dir written by Setup.Snapshot, instead of Build.
+// s must have the same chains, relayers and links as the Setup the snapshot was taken from,
+// but it may belong to another test.
+//
+// The nodes get new containers and port bindings, and the references to the hosts and home directories
+// of the snapshot nodes in the configuration of the nodes and relayers are replaced by the restored ones,
+// as are the host addresses of the chains in the configuration of the relayers running on the host.
+// Sidecars start from scratch, as they are not part of the snapshot.
+func RestoreSetup(ctx context.Context, s *Setup, opts InterchainBuildOptions, dir string) error {
+	b, err := os.ReadFile(filepath.Join(dir, snapshotManifestFile))
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot manifest: %w", err)
+	}
+	var manifest networkSnapshot
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return fmt.Errorf("failed to decode snapshot manifest: %w", err)
+	}
+
+	chains := make([]ibc.Chain, 0, len(s.chains))
+	for chain := range s.chains {
+		chains = append(chains, chain)
+	}
+	s.cs = newChainSet(s.log, chains)
+
+	if err := s.preflightImages(ctx, opts.Client); err != nil {
+		return fmt.Errorf("failed image preflight: %w", err)
+	}
+
+	if err := s.cs.Initialize(ctx, opts.TestName, opts.Client, opts.NetworkID); err != nil {
+		return fmt.Errorf("failed to initialize chains: %w", err)
+	}
+
+	// Map the hosts and home directories of the snapshot nodes to the restored ones.
+	var replacements [][2]string
+	for c := range s.chains {
+		chainID := c.Config().ChainID
+		sc, ok := c.(ibc.Snapshotter)
+		if !ok {
+			return fmt.Errorf("chain %s does not support snapshots", chainID)
+		}
+		nodes, ok := manifest.Chains[chainID]
+		if !ok {
+			return fmt.Errorf("chain %s is not in the snapshot", chainID)
+		}
+		for _, n := range sc.DescribeNodes() {
+			for _, old := range nodes {
+				if old.Validator == n.Validator && old.Index == n.Index {
+					replacements = append(replacements, [2]string{old.HostName, n.HostName}, [2]string{old.HomeDir, n.HomeDir})
+				}
+			}
+		}
+	}
+	rewrite := snapshotRewriter(replacements)
+
+	var eg errgroup.Group
+	for c := range s.chains {
+		c := c
+		chainID := c.Config().ChainID
+		eg.Go(func() error {
+			if err := c.(ibc.Snapshotter).RestoreNodes(ctx, filepath.Join(dir, chainID), manifest.Chains[chainID], rewrite); err != nil {
+				return fmt.Errorf("failed to restore chain %s: %w", chainID, err)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	// The host ports of the restored nodes are known once they are started.
+	hostReplacements := append([][2]string{}, replacements...)
+	for c := range s.chains {
+		old, ok := manifest.HostAddresses[c.Config().ChainID]
+		if !ok {
+			continue
+		}
+		restored := chainHostAddresses(c)
+		hostReplacements = append(hostReplacements, [2]string{old.RPC, restored.RPC}, [2]string{old.GRPC, restored.GRPC}, [2]string{old.API, restored.API})
+	}
+	hostRewrite := snapshotRewriter(hostReplacements)
+
+	for r, name := range s.relayers {
+		rs, ok := manifest.Relayers[name]
+		if !ok {
+			return fmt.Errorf("relayer %s is not in the snapshot", name)
+		}
+		relayerRewrite := rewrite
+		if !r.UseDockerNetwork() {
+			relayerRewrite = hostRewrite
+		}
+		if err := restoreRelayerHome(filepath.Join(dir, rs.Archive), s.relayerHostHomeDir(r), relayerRewrite); err != nil {
+			return fmt.Errorf("failed to restore relayer %s: %w", name, err)
+		}
+	}
+	s.watchHostAddresses(ibc.NopRelayerExecReporter{})
+
+	s.relayerWallets = make(map[relayerChain]ibc.Wallet)
+	for r, chains := range s.relayerChains() {
+		name := s.relayers[r]
+		rs := manifest.Relayers[name]
+		for _, c := range chains {
+			chainID := c.Config().ChainID
+			ws, ok := rs.Wallets[chainID]
+			if !ok {
+				return fmt.Errorf("wallet of relayer %s for chain %s is not in the snapshot", name, chainID)
+			}
+			wallet := restoredWallet{w: ws}
+			s.relayerWallets[relayerChain{R: r, C: c}] = wallet
+			if wr, ok := r.(interface{ AddWallet(string, ibc.Wallet) }); ok {
+				wr.AddWallet(chainID, wallet)
+			}
+		}
+	}
+
+	if err := s.cs.TrackBlocks(ctx, opts.TestName, opts.BlockDatabaseFile, opts.GitSha, opts.Client, opts.ContainerStatsInterval); err != nil {
+		return fmt.Errorf("failed to track blocks: %w", err)
+	}
+
+	return nil
+}
+
+// snapshotRewriter returns a function replacing the old strings of replacements with the new ones,
+// the longest first, so a host name is not mistaken for the prefix of another.
+func snapshotRewriter(replacements [][2]string) func(string) string {
+	sort.SliceStable(replacements, func(i, j int) bool {
+		return len(replacements[i][0]) > len(replacements[j][0])
+	})
+	oldnew := make([]string, 0, 2*len(replacements))
+	for _, r := range replacements {
+		if r[0] != "" && r[0] != r[1] {
+			oldnew = append(oldnew, r[0], r[1])
+		}
+	}
+	return strings.NewReplacer(oldnew...).Replace
+}
+
+func writeDirArchive(file, dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := dockerutil.TarDir(f, dir); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// restoreRelayerHome extracts the relayer home directory archived in file into dir,
+// passing its configuration files through rewrite.
+func restoreRelayerHome(file, dir string, rewrite func(string) string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	rewriteConfig := func(name string, content []byte) []byte {
+		switch path.Ext(name) {
+		case ".yaml", ".yml", ".json", ".toml", ".config":
+			return []byte(rewrite(string(content)))
+		}
+		return content
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(dockerutil.RewriteTar(pw, f, nil, rewriteConfig))
+	}()
+	defer pr.Close()
+
+	return dockerutil.UntarDir(dir, pr)
+}
@@ -0,0 +1,225 @@
+package rollupe2etesting
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/decentrio/rollup-e2e-testing/cosmos"
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+	"github.com/decentrio/rollup-e2e-testing/ibc"
+	"github.com/decentrio/rollup-e2e-testing/relayer"
+	rly "github.com/decentrio/rollup-e2e-testing/relayer/rly"
+)
+
+// cometStatusPort serves the status of a node that caught up, as the RPC of a started node,
+// and returns its port.
+func cometStatusPort(t *testing.T) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":{` +
+			`"node_info":{"protocol_version":{"p2p":"8","block":"11","app":"0"},"other":{}},` +
+			`"sync_info":{"latest_block_height":"5","latest_block_time":"2024-01-01T00:00:00Z",` +
+			`"earliest_block_height":"1","earliest_block_time":"2024-01-01T00:00:00Z","catching_up":false},` +
+			`"validator_info":{"voting_power":"0"}}}`))
+	}))
+	t.Cleanup(srv.Close)
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	return port
+}
+
+func TestSnapshotRestoreSetup(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	log := zaptest.NewLogger(t)
+	image := ibc.DockerImage{Repository: "dymd", Version: "v1", UidGid: "1025:1025"}
+	snapshotTest, restoreTest := t.Name()+"-snapshot", t.Name()+"-restore"
+
+	// The nodes of each setup answer on their own RPC port of the host.
+	rpcPorts := map[string]string{
+		dockerutil.SanitizeContainerName(snapshotTest): cometStatusPort(t),
+		dockerutil.SanitizeContainerName(restoreTest):  cometStatusPort(t),
+	}
+	rt := dockerutil.NewFakeRuntime()
+	rt.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		if len(cmd) > 1 && cmd[0] == "dymd" && cmd[1] == "start" {
+			return dockerutil.FakeResult{Running: true}
+		}
+		return dockerutil.FakeResult{}
+	}
+	rt.HostPortFunc = func(containerName string, port nat.Port) string {
+		if port != "26657/tcp" {
+			return ""
+		}
+		for testName, rpcPort := range rpcPorts {
+			if strings.HasSuffix(containerName, testName) {
+				return rpcPort
+			}
+		}
+		return ""
+	}
+
+	newSetup := func(testName string) (*Setup, *cosmos.CosmosChain, *relayer.LocalRelayer) {
+		chain := cosmos.NewCosmosChain(testName, ibc.ChainConfig{
+			Type:    "hub-dym",
+			Name:    "dymension",
+			ChainID: "dymension_100-1",
+			Bin:     "dymd",
+			Images:  []ibc.DockerImage{image},
+		}, 1, 0, log)
+		r, err := rly.NewLocalCosmosRelayer(log, testName, "rly", relayer.LocalBinary("true"), relayer.HomeDir(t.TempDir()))
+		require.NoError(t, err)
+		return NewSetup().WithLog(log).AddChain(chain).AddRelayer(r, "rly"), chain, r.LocalRelayer
+	}
+	relayerConfig := func(c *cosmos.CosmosChain) string {
+		return "rpc-addr: " + c.GetHostRPCAddress() + "\ngrpc-addr: " + c.GetHostGRPCAddress() + "\n"
+	}
+
+	// Start the network to snapshot, as Build would.
+	s, chain, r := newSetup(snapshotTest)
+	s.cs = newChainSet(log, []ibc.Chain{chain})
+	require.NoError(t, s.cs.Initialize(ctx, snapshotTest, rt, "net"))
+	node := chain.Validators[0]
+	require.NoError(t, node.WriteFile(ctx, []byte("moniker = \""+node.HostName()+"\"\n"), "config/config.toml"))
+	require.NoError(t, node.CreateNodeContainer(ctx, nil))
+	require.NoError(t, node.StartContainer(ctx))
+	require.NoError(t, os.MkdirAll(filepath.Join(r.HomeDir(), "config"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(r.HomeDir(), "config", "config.yaml"), []byte(relayerConfig(chain)), 0o644))
+	// The relayer follows the node to its new host ports when it restarts for the snapshot, as Build sets up.
+	chain.OnHostAddressesChange(func(ctx context.Context, old, new ibc.HostAddresses) error {
+		return r.UpdateHostAddresses(ctx, ibc.NopRelayerExecReporter{}, old, new)
+	})
+
+	dir := t.TempDir()
+	require.NoError(t, s.Snapshot(ctx, dir))
+
+	restored, restoredChain, restoredRelayer := newSetup(restoreTest)
+	require.NoError(t, RestoreSetup(ctx, restored, InterchainBuildOptions{TestName: restoreTest, Client: rt, NetworkID: "net"}, dir))
+	t.Cleanup(func() { _ = restored.Close() })
+
+	// The node configuration refers to the restored node.
+	restoredNode := restoredChain.Validators[0]
+	config, err := restoredNode.ReadFile(ctx, "config/config.toml")
+	require.NoError(t, err)
+	require.Equal(t, "moniker = \""+restoredNode.HostName()+"\"\n", string(config))
+	require.NotEqual(t, node.HostName(), restoredNode.HostName())
+
+	// The host relayer reaches the restored node on its new host ports.
+	require.NotEqual(t, chain.GetHostRPCAddress(), restoredChain.GetHostRPCAddress())
+	require.NotEqual(t, chain.GetHostGRPCAddress(), restoredChain.GetHostGRPCAddress())
+	config, err = os.ReadFile(filepath.Join(restoredRelayer.HomeDir(), "config", "config.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, relayerConfig(restoredChain), string(config))
+}
+
+// snapshotOrderRuntime records the node containers stopped, archived and started, in order.
+type snapshotOrderRuntime struct {
+	dockerutil.Runtime
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *snapshotOrderRuntime) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *snapshotOrderRuntime) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	r.record("stop")
+	return r.Runtime.ContainerStop(ctx, containerID, options)
+}
+
+func (r *snapshotOrderRuntime) ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error {
+	r.record("start")
+	return r.Runtime.ContainerStart(ctx, containerID, options)
+}
+
+func (r *snapshotOrderRuntime) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+	r.record("archive")
+	return r.Runtime.CopyFromContainer(ctx, containerID, srcPath)
+}
+
+func TestSnapshotStopsAllNodesFirst(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	log := zaptest.NewLogger(t)
+	rpcPort := cometStatusPort(t)
+	fake := dockerutil.NewFakeRuntime()
+	fake.RunFunc = func(containerName string, cmd []string) dockerutil.FakeResult {
+		return dockerutil.FakeResult{Running: len(cmd) > 1 && cmd[0] == "dymd" && cmd[1] == "start"}
+	}
+	fake.HostPortFunc = func(containerName string, port nat.Port) string {
+		if port == "26657/tcp" {
+			return rpcPort
+		}
+		return ""
+	}
+	rt := &snapshotOrderRuntime{Runtime: fake}
+
+	var chains []ibc.Chain
+	s := NewSetup().WithLog(log)
+	for _, chainID := range []string{"dymension_100-1", "dymension_200-1"} {
+		chain := cosmos.NewCosmosChain(t.Name(), ibc.ChainConfig{
+			Type:    "hub-dym",
+			Name:    chainID,
+			ChainID: chainID,
+			Bin:     "dymd",
+			Images:  []ibc.DockerImage{{Repository: "dymd", Version: "v1", UidGid: "1025:1025"}},
+		}, 2, 0, log)
+		s.AddChain(chain)
+		chains = append(chains, chain)
+	}
+	s.cs = newChainSet(log, chains)
+	require.NoError(t, s.cs.Initialize(ctx, t.Name(), rt, "net"))
+	var eg errgroup.Group
+	for _, c := range chains {
+		for _, node := range c.(*cosmos.CosmosChain).Validators {
+			node := node
+			eg.Go(func() error {
+				if err := node.WriteFile(ctx, []byte("moniker = \""+node.HostName()+"\"\n"), "config/config.toml"); err != nil {
+					return err
+				}
+				if err := node.CreateNodeContainer(ctx, nil); err != nil {
+					return err
+				}
+				return node.StartContainer(ctx)
+			})
+		}
+	}
+	require.NoError(t, eg.Wait())
+
+	rt.mu.Lock()
+	rt.events = nil
+	rt.mu.Unlock()
+	require.NoError(t, s.Snapshot(ctx, t.TempDir()))
+
+	// No node is archived before every node of every chain is stopped, and none starts before all are archived.
+	require.Equal(t, []string{"stop", "stop", "stop", "stop", "archive", "archive", "archive", "archive", "start", "start", "start", "start"}, rt.events)
+}
@@ -129,6 +129,68 @@ func (m RelayerExecMessage) typ() string {
 	return "RelayerExec"
 }
 
+// RelayerLogMessage is a single log line of a running relayer.
+// This message is populated through the RelayerExecReporter type.
+type RelayerLogMessage struct {
+	Name string // Test name, but "Name" for consistency.
+
+	When time.Time
+
+	ContainerName string `json:",omitempty"`
+
+	Stream string
+	Line   string
+}
+
+func (m RelayerLogMessage) typ() string {
+	return "RelayerLog"
+}
+
+// ChaosMessage is a fault injected into, or recovered from, a container during a test.
+// This message is populated through the ChaosReporter type,
+// which is returned by the Reporter's ChaosReporter method.
+type ChaosMessage struct {
+	Name string // Test name, but "Name" for consistency.
+
+	When time.Time
+
+	Target string
+	Action string
+
+	Error string `json:",omitempty"`
+}
+
+func (m ChaosMessage) typ() string {
+	return "Chaos"
+}
+
+// messageTestName returns the name of the test m is about,
+// or an empty string if m is not about a single test.
+func messageTestName(m Message) string {
+	switch m := m.(type) {
+	case BeginTestMessage:
+		return m.Name
+	case FinishTestMessage:
+		return m.Name
+	case PauseTestMessage:
+		return m.Name
+	case ContinueTestMessage:
+		return m.Name
+	case TestErrorMessage:
+		return m.Name
+	case TestSkipMessage:
+		return m.Name
+	case RelayerExecMessage:
+		return m.Name
+	case RelayerLogMessage:
+		return m.Name
+	case ChaosMessage:
+		return m.Name
+	default:
+		return ""
+	}
+}
+
 // WrappedMessage wraps a Message with an outer Type field
 // so that decoders can determine the underlying message's type.
 type WrappedMessage struct {
@@ -195,6 +257,14 @@ func (m *WrappedMessage) UnmarshalJSON(b []byte) error {
 		x := RelayerExecMessage{}
 		err = json.Unmarshal(raw, &x)
 		msg = x
+	case "RelayerLog":
+		x := RelayerLogMessage{}
+		err = json.Unmarshal(raw, &x)
+		msg = x
+	case "Chaos":
+		x := ChaosMessage{}
+		err = json.Unmarshal(raw, &x)
+		msg = x
 	default:
 		return fmt.Errorf("unknown message type %q", outer.Type)
 	}
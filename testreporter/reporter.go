@@ -1,10 +1,15 @@
 package testreporter
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
 )
 
 // T is a subset of testing.TB,
@@ -27,6 +32,16 @@ type Reporter struct {
 	in chan Message
 
 	writerDone chan error
+
+	mu sync.Mutex
+	// Written messages of tests, kept for TestMessagesJSON while failure bundles are written,
+	// until the test finishes without failing.
+	testMessages []testMessage
+}
+
+type testMessage struct {
+	name string
+	line []byte
 }
 
 func NewReporter(w io.WriteCloser) *Reporter {
@@ -47,18 +62,59 @@ func NewReporter(w io.WriteCloser) *Reporter {
 // Allowing all writes to happen in a single goroutine avoids any lock contention
 // that could happen with a mutex guarding concurrent writes to the io.Writer.
 func (r *Reporter) write() {
-	enc := json.NewEncoder(r.w)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
 	enc.SetEscapeHTML(false)
 
 	for m := range r.in {
+		buf.Reset()
 		if err := enc.Encode(JSONMessage(m)); err != nil {
 			panic(fmt.Errorf("reporter failed to encode message; tests cannot continue: %w", err))
 		}
+		if _, err := r.w.Write(buf.Bytes()); err != nil {
+			panic(fmt.Errorf("reporter failed to write message; tests cannot continue: %w", err))
+		}
+
+		r.retainTestMessage(m, buf.Bytes())
 	}
 
 	r.writerDone <- r.w.Close()
 }
 
+// retainTestMessage keeps the written line of m for TestMessagesJSON, when failure bundles are written,
+// and drops the lines of a test once it finished without failing.
+func (r *Reporter) retainTestMessage(m Message, line []byte) {
+	name := messageTestName(m)
+	if name == "" || dockerutil.FailureBundleDir == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if finish, ok := m.(FinishTestMessage); ok && !finish.Failed {
+		kept := r.testMessages[:0]
+		for _, tm := range r.testMessages {
+			if !isTestOrSubtest(tm.name, name) {
+				kept = append(kept, tm)
+			}
+		}
+		clear(r.testMessages[len(kept):])
+		r.testMessages = kept
+		return
+	}
+
+	r.testMessages = append(r.testMessages, testMessage{
+		name: name,
+		line: bytes.Clone(line),
+	})
+}
+
+// isTestOrSubtest reports whether the test named name is the test named parent or one of its subtests.
+func isTestOrSubtest(name, parent string) bool {
+	return name == parent || strings.HasPrefix(name, parent+"/")
+}
+
 // Close closes the reporter and blocks until its results are flushed
 // to the underlying writer.
 func (r *Reporter) Close() error {
@@ -69,6 +125,22 @@ func (r *Reporter) Close() error {
 	return <-r.writerDone
 }
 
+// TestMessagesJSON returns the messages written so far of the test named name and of its subtests,
+// in the JSON lines format of the reporter output.
+// Messages are only kept while dockerutil.FailureBundleDir is set, and until their test passes.
+func (r *Reporter) TestMessagesJSON(name string) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []byte
+	for _, m := range r.testMessages {
+		if isTestOrSubtest(m.name, name) {
+			out = append(out, m.line...)
+		}
+	}
+	return out
+}
+
 // trackTest tracks the test start and finish time.
 // It also records which labels are present on the test.
 func (r *Reporter) TrackTest(t T) {
@@ -123,7 +195,7 @@ func (r *Reporter) RelayerExecReporter(t T) *RelayerExecReporter {
 	return &RelayerExecReporter{r: r, testName: t.Name()}
 }
 
-// RelayerExecReporter provides one method that satisfies the ibc.RelayerExecReporter interface.
+// RelayerExecReporter satisfies the ibc.RelayerExecReporter and ibc.RelayerLogReporter interfaces.
 // Instances of RelayerExecReporter must be retrieved through (*Reporter).RelayerExecReporter.
 type RelayerExecReporter struct {
 	r        *Reporter
@@ -156,6 +228,44 @@ func (r *RelayerExecReporter) TrackRelayerExec(
 	}
 }
 
+// TrackRelayerLog tracks a single log line of a running relayer.
+func (r *RelayerExecReporter) TrackRelayerLog(containerName, stream, line string, when time.Time) {
+	r.r.in <- RelayerLogMessage{
+		Name:          r.testName,
+		When:          when,
+		ContainerName: containerName,
+		Stream:        stream,
+		Line:          line,
+	}
+}
+
+// ChaosReporter returns a ChaosReporter associated with t.
+func (r *Reporter) ChaosReporter(t T) *ChaosReporter {
+	return &ChaosReporter{r: r, testName: t.Name()}
+}
+
+// ChaosReporter satisfies the cosmos.ChaosReporter interface.
+// Instances of ChaosReporter must be retrieved through (*Reporter).ChaosReporter.
+type ChaosReporter struct {
+	r        *Reporter
+	testName string
+}
+
+// TrackChaos tracks a single chaos action applied to target.
+func (r *ChaosReporter) TrackChaos(target, action string, when time.Time, err error) {
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+	r.r.in <- ChaosMessage{
+		Name:   r.testName,
+		When:   when,
+		Target: target,
+		Action: action,
+		Error:  errMsg,
+	}
+}
+
 // TestifyT returns a TestifyReporter which will track logged errors in test.
 // Typically you will use this with the New method on the require or assert package:
 //
@@ -0,0 +1,57 @@
+package testreporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/decentrio/rollup-e2e-testing/dockerutil"
+)
+
+func TestReporterTestMessages(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		bundleDir string
+		want      []string
+	}{
+		{name: "no failure bundles"},
+		{name: "failure bundles", bundleDir: t.TempDir(), want: []string{
+			`"Name":"TestFail"`, `"Name":"TestFail/fail"`, `"Name":"TestFail/fail"`, `"Name":"TestFail"`,
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bundleDir := dockerutil.FailureBundleDir
+			dockerutil.FailureBundleDir = tc.bundleDir
+			t.Cleanup(func() { dockerutil.FailureBundleDir = bundleDir })
+
+			r := NewNopReporter()
+			now := time.Now()
+			for _, m := range []Message{
+				BeginTestMessage{Name: "TestPass", StartedAt: now},
+				BeginTestMessage{Name: "TestFail", StartedAt: now},
+				BeginTestMessage{Name: "TestFail/pass", StartedAt: now},
+				FinishTestMessage{Name: "TestFail/pass", FinishedAt: now},
+				BeginTestMessage{Name: "TestFail/fail", StartedAt: now},
+				FinishTestMessage{Name: "TestFail/fail", FinishedAt: now, Failed: true},
+				FinishTestMessage{Name: "TestPass", FinishedAt: now},
+				FinishTestMessage{Name: "TestFail", FinishedAt: now, Failed: true},
+			} {
+				r.in <- m
+			}
+			require.NoError(t, r.Close())
+
+			// Only the messages of failed tests are kept.
+			require.Empty(t, r.TestMessagesJSON("TestPass"))
+			var lines []string
+			if out := strings.TrimSuffix(string(r.TestMessagesJSON("TestFail")), "\n"); out != "" {
+				lines = strings.Split(out, "\n")
+			}
+			require.Len(t, lines, len(tc.want))
+			for i, want := range tc.want {
+				require.Contains(t, lines[i], want)
+			}
+		})
+	}
+}
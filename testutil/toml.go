@@ -8,7 +8,6 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/decentrio/rollup-e2e-testing/dockerutil"
-	"github.com/docker/docker/client"
 	"go.uber.org/zap"
 )
 
@@ -46,7 +45,7 @@ func recursiveModifyToml(c map[string]any, modifications Toml) error {
 func ModifyTomlConfigFile(
 	ctx context.Context,
 	logger *zap.Logger,
-	dockerClient *client.Client,
+	dockerClient dockerutil.Runtime,
 	testName string,
 	volumeName string,
 	chainName string,